@@ -47,3 +47,27 @@ resource "orbstack_machine" "demo" {
           - ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhA...
   EOF
 }
+
+resource "orbstack_machine" "cloud_config" {
+  name  = "ci-structured"
+  image = "ubuntu"
+
+  cloud_config {
+    package_update = true
+    packages       = ["git", "curl"]
+    timezone       = "UTC"
+
+    users {
+      name                = "hank"
+      groups              = ["users", "admin"]
+      shell               = "/bin/bash"
+      sudo                = "ALL=(ALL) NOPASSWD:ALL"
+      ssh_authorized_keys = [chomp(file("~/.ssh/id_rsa.pub"))]
+    }
+
+    write_files {
+      path    = "/etc/motd"
+      content = "Managed by Terraform\n"
+    }
+  }
+}
@@ -2,6 +2,7 @@ output "machine_info" {
   value = {
     id         = data.orbstack_machine.example.id
     ip_address = data.orbstack_machine.example.ip_address
+    fqdn       = data.orbstack_machine.example.fqdn
     status     = data.orbstack_machine.example.status
     ssh_host   = data.orbstack_machine.example.ssh_host
     ssh_port   = data.orbstack_machine.example.ssh_port
@@ -10,3 +11,7 @@ output "machine_info" {
 
 
 
+
+output "ssh_command" {
+  value = data.orbstack_machine.default_info.ssh_command
+}
@@ -9,25 +9,17 @@ terraform {
 
 provider "orbstack" {}
 
-# Create a machine that will be set as the default
-resource "orbstack_machine" "default_vm" {
-  name            = "default-vm"
-  image           = "ubuntu:noble"
-  default_machine = true
-}
-
-# Create another machine (not default)
-resource "orbstack_machine" "secondary_vm" {
-  name  = "secondary-vm"
+resource "orbstack_machine" "dev" {
+  name  = "default-dev"
   image = "ubuntu:noble"
 }
 
-# Output the default machine name
-output "default_machine_name" {
-  value = orbstack_machine.default_vm.name
+resource "orbstack_machine" "scratch" {
+  name  = "default-scratch"
+  image = "alpine"
 }
 
-# Output whether the default machine is set
-output "is_default_machine" {
-  value = orbstack_machine.default_vm.default_machine
+# One place decides which machine `orb` connects to
+resource "orbstack_default_machine" "this" {
+  machine = orbstack_machine.dev.name
 }
@@ -0,0 +1,34 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+variable "rootfs" {
+  description = "Root filesystem tarball built in CI"
+  type        = string
+  default     = "./build/rootfs.tar.zst"
+}
+
+variable "rootfs_sha256" {
+  description = "Checksum published by the CI build; leave null to trust the file"
+  type        = string
+  default     = null
+}
+
+# Replaced whenever CI publishes a new image at the same path
+resource "orbstack_machine" "hardened" {
+  name                = "hardened"
+  image_source        = var.rootfs
+  image_source_sha256 = var.rootfs_sha256
+  update_strategy     = "blue_green"
+}
+
+output "image_sha256" {
+  value = orbstack_machine.hardened.image_source_sha256
+}
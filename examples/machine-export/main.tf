@@ -0,0 +1,30 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+resource "orbstack_machine" "dev" {
+  name  = "export-dev"
+  image = "ubuntu:noble"
+}
+
+resource "orbstack_machine_export" "dev" {
+  machine = orbstack_machine.dev.name
+  path    = "${path.module}/backups/export-dev.tar.zst"
+}
+
+resource "orbstack_machine" "restored" {
+  name                = "export-dev-restored"
+  image_source        = orbstack_machine_export.dev.id
+  image_source_sha256 = orbstack_machine_export.dev.sha256
+}
+
+output "archive_sha256" {
+  value = orbstack_machine_export.dev.sha256
+}
@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+resource "orbstack_machine" "alpine" {
+  name  = "packages-alpine"
+  image = "alpine"
+}
+
+# The same list works on any supported distribution
+resource "orbstack_machine_packages" "tools" {
+  machine  = orbstack_machine.alpine.name
+  packages = ["curl", "git", "jq"]
+}
+
+output "installed" {
+  value = orbstack_machine_packages.tools.installed_versions
+}
@@ -0,0 +1,21 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+resource "orbstack_machine_pool" "etcd" {
+  size               = 3
+  name_pattern       = "etcd-%d"
+  image              = "ubuntu:noble"
+  create_parallelism = 2
+}
+
+output "etcd_members" {
+  value = zipmap(orbstack_machine_pool.etcd.names, orbstack_machine_pool.etcd.ip_addresses)
+}
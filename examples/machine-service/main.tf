@@ -0,0 +1,36 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+# Alpine machines use OpenRC; the resource detects it
+resource "orbstack_machine" "alpine" {
+  name  = "service-alpine"
+  image = "alpine"
+}
+
+resource "orbstack_machine_packages" "nginx" {
+  machine  = orbstack_machine.alpine.name
+  packages = ["nginx"]
+}
+
+resource "orbstack_machine_service" "nginx" {
+  machine = orbstack_machine.alpine.name
+  name    = "nginx"
+  enabled = true
+  state   = "running"
+
+  restart_triggers = {
+    version = orbstack_machine_packages.nginx.installed_versions["nginx"]
+  }
+}
+
+output "nginx" {
+  value = "${orbstack_machine_service.nginx.init_system}: ${orbstack_machine_service.nginx.active_state}"
+}
@@ -0,0 +1,27 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+resource "orbstack_machine" "dev" {
+  name  = "settings-dev"
+  image = "ubuntu:noble"
+}
+
+resource "orbstack_machine_settings" "dev" {
+  machine          = orbstack_machine.dev.name
+  timezone         = "Europe/Amsterdam"
+  locale           = "en_US.UTF-8"
+  hostname_aliases = ["api.dev.local", "db.dev.local"]
+
+  environment = {
+    APP_ENV   = "development"
+    LOG_LEVEL = "debug"
+  }
+}
@@ -0,0 +1,28 @@
+terraform {
+  required_providers {
+    orbstack = {
+      source  = "robertdebock/orbstack"
+      version = ">= 3.1.0"
+    }
+  }
+}
+
+provider "orbstack" {}
+
+resource "orbstack_machine" "lab" {
+  name  = "lab"
+  image = "ubuntu:noble"
+}
+
+resource "orbstack_machine_user" "ansible" {
+  machine             = orbstack_machine.lab.name
+  name                = "ansible"
+  groups              = ["adm"]
+  shell               = "/bin/bash"
+  sudo                = true
+  ssh_authorized_keys = [file("~/.ssh/id_ed25519.pub")]
+}
+
+output "ansible_home" {
+  value = orbstack_machine_user.ansible.home
+}
@@ -35,3 +35,8 @@ resource "orbstack_machine" "username_arch" {
   username = "demo"
   arch     = "arm64"
 }
+
+resource "orbstack_machine" "clone" {
+  name       = "example-vm-clone"
+  clone_from = orbstack_machine.example.name
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultBackupRetention is the number of archives kept per machine when
+// backup_on_destroy.retention is not set.
+const defaultBackupRetention = 5
+
+// backupTimeFormat sorts lexically in chronological order.
+const backupTimeFormat = "20060102T150405Z"
+
+// BackupOnDestroyModel maps the backup_on_destroy block of orbstack_machine.
+type BackupOnDestroyModel struct {
+	Directory types.String `tfsdk:"directory"`
+	Retention types.Int64  `tfsdk:"retention"`
+}
+
+// backupMachine exports a machine to a timestamped archive in the configured
+// directory and prunes the oldest archives of that machine past the retention.
+func backupMachine(ctx context.Context, cfg *ClientConfig, name string, b *BackupOnDestroyModel) (string, error) {
+	dir, err := filepath.Abs(b.Directory.ValueString())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve backup directory: %w", err)
+	}
+
+	dest := filepath.Join(dir, fmt.Sprintf("%s-%s.tar.zst", name, time.Now().UTC().Format(backupTimeFormat)))
+	if err := exportMachine(ctx, cfg, name, dest); err != nil {
+		return "", err
+	}
+
+	retention := defaultBackupRetention
+	if !b.Retention.IsNull() && !b.Retention.IsUnknown() {
+		retention = int(b.Retention.ValueInt64())
+	}
+	if err := pruneBackups(dir, name, retention); err != nil {
+		return dest, fmt.Errorf("backup written to %s, but pruning old backups failed: %w", dest, err)
+	}
+	return dest, nil
+}
+
+// pruneBackups removes all but the newest keep archives of a machine. Only
+// files named like backupMachine's archives are considered, so "dev" never
+// prunes the archives of "dev-2".
+func pruneBackups(dir, name string, keep int) error {
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(name) + `-[0-9]{8}T[0-9]{6}Z\.tar\.zst$`)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var archives []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && pattern.MatchString(e.Name()) {
+			archives = append(archives, e.Name())
+		}
+	}
+	sort.Strings(archives)
+
+	for len(archives) > keep {
+		if err := os.Remove(filepath.Join(dir, archives[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		archives = archives[1:]
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const blueGreenReplaceDescription = "Changing this attribute requires a new machine. With update_strategy = blue_green, the new machine is built next to the current one and swapped in place."
+
+// isBlueGreen reports whether the planned update_strategy is blue_green.
+func isBlueGreen(ctx context.Context, plan tfsdk.Plan) bool {
+	var strategy types.String
+	if diags := plan.GetAttribute(ctx, path.Root("update_strategy"), &strategy); diags.HasError() {
+		return false
+	}
+	return strategy.ValueString() == "blue_green"
+}
+
+// replaceUnlessBlueGreen requires replacement on change, unless Update rebuilds
+// the machine with the blue/green strategy instead.
+func replaceUnlessBlueGreen() planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = !isBlueGreen(ctx, req.Plan)
+		},
+		blueGreenReplaceDescription,
+		blueGreenReplaceDescription,
+	)
+}
+
+func replaceObjectUnlessBlueGreen(ctx context.Context, req planmodifier.ObjectRequest, resp *objectplanmodifier.RequiresReplaceIfFuncResponse) {
+	resp.RequiresReplace = !isBlueGreen(ctx, req.Plan)
+}
+
+// machineNeedsRebuild reports whether plan changes an attribute that can only
+// be applied to a new machine. An unknown resolved_image means pin_image found
+// a new release; a new image_source_sha256 means the image file changed.
+func machineNeedsRebuild(plan, state *MachineModel) bool {
+	if !plan.Image.Equal(state.Image) ||
+		!plan.CloudInit.Equal(state.CloudInit) ||
+		!plan.CloudInitFile.Equal(state.CloudInitFile) ||
+		!plan.CloneFrom.Equal(state.CloneFrom) ||
+		!plan.SourceArchive.Equal(state.SourceArchive) ||
+		!plan.ImageSource.Equal(state.ImageSource) ||
+		!plan.Arch.Equal(state.Arch) {
+		return true
+	}
+	if !plan.Username.IsUnknown() && !state.Username.IsNull() && !plan.Username.Equal(state.Username) {
+		return true
+	}
+	if plan.ResolvedImage.IsUnknown() && !state.ResolvedImage.IsNull() {
+		return true
+	}
+	if !plan.ImageSourceSHA256.IsUnknown() && !state.ImageSourceSHA256.IsNull() && !plan.ImageSourceSHA256.Equal(state.ImageSourceSHA256) {
+		return true
+	}
+	return !reflect.DeepEqual(plan.CloudConfig, state.CloudConfig)
+}
+
+// planBlueGreen marks the attributes that belong to the old machine as unknown
+// when the blue/green strategy rebuilds it.
+func (r *MachineResource) planBlueGreen(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || !isBlueGreen(ctx, resp.Plan) {
+		return
+	}
+
+	var plan, state MachineModel
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() || !machineNeedsRebuild(&plan, &state) {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("resolved_image"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("emulated"), types.BoolUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ownership_token"), types.StringUnknown())...)
+}
+
+// blueGreenName returns a unique machine name derived from name, shortened so
+// that it stays within 63 characters.
+func blueGreenName(name, role string) (string, error) {
+	base := name
+	if limit := 63 - len(role) - 2 - machineNameSuffixLen; len(base) > limit {
+		base = strings.TrimRight(base[:limit], "-")
+	}
+	return uniqueMachineName(base + "-" + role + "-")
+}
+
+// blueGreenReplace builds the planned machine under a temporary name, waits for
+// it to become ready, swaps it in with orb rename and deletes the old machine.
+// Until the swap, any failure deletes the new machine and leaves the old one
+// untouched; once the swap succeeded, later problems are only warnings.
+func (r *MachineResource) blueGreenReplace(ctx context.Context, config tfsdk.Config, plan, state *MachineModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	cfg := r.client
+	oldName, newName := state.Name.ValueString(), plan.Name.ValueString()
+
+	// The old machine is deleted, so the checks of Delete apply
+	if state.DeletionProtection.ValueBool() {
+		diags.AddError(
+			"machine is protected from deletion",
+			fmt.Sprintf("Machine %q has deletion_protection enabled. Set deletion_protection = false and apply before replacing it.", oldName),
+		)
+		return diags
+	}
+	if err := checkMachineOwner(ctx, cfg, oldName, state.OwnershipToken.ValueString()); err != nil {
+		diags.AddError("machine is not owned by this resource", err.Error())
+		return diags
+	}
+	if newName != oldName && machineExists(ctx, cfg, newName) {
+		diags.AddError("machine already exists", fmt.Sprintf("A machine named %q already exists and was not created by this resource.", newName))
+		return diags
+	}
+
+	nextName, err := blueGreenName(newName, "next")
+	if err != nil {
+		diags.AddError("failed to generate machine name", err.Error())
+		return diags
+	}
+
+	// Cleanup must run even when the apply was interrupted
+	cleanupCtx := context.WithoutCancel(ctx)
+	discardNext := func() {
+		if machineExists(cleanupCtx, cfg, nextName) {
+			if _, stderr, err := runOrb(cleanupCtx, cfg.OrbPath, "delete", nextName); err != nil {
+				diags.AddWarning("failed to roll back", fmt.Sprintf("Delete machine %q manually. orb error: %s", nextName, strings.TrimSpace(stderr)))
+			}
+		}
+	}
+
+	tflog.Info(ctx, "blue/green: creating new machine", map[string]any{"machine": oldName, "next": nextName})
+	diags.Append(provisionMachine(ctx, cfg, plan, nextName)...)
+	if diags.HasError() {
+		discardNext()
+		return diags
+	}
+
+	model, d := readUntilReady(ctx, cfg, nextName, cfg.CreateTimeout)
+	diags.Append(d...)
+	if diags.HasError() || model == nil || !isMachineReady(model) {
+		if !diags.HasError() {
+			diags.AddError("machine did not become ready", fmt.Sprintf("New machine %q did not report an IP address within create_timeout (%s).", nextName, parseTimeout(cfg.CreateTimeout, 30*time.Second)))
+		}
+		discardNext()
+		return diags
+	}
+
+	if plan.Username.IsUnknown() || plan.Username.IsNull() {
+		user, err := machineDefaultUser(ctx, cfg, nextName)
+		if err != nil {
+			diags.AddError("failed to determine machine user", err.Error())
+			discardNext()
+			return diags
+		}
+		plan.Username = types.StringValue(user)
+	}
+	copyMachineInfo(plan, model)
+
+	// Write-only: the password is only available in config
+	var password types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("password"), &password)...)
+	if !password.IsNull() && !password.IsUnknown() {
+		if err := setMachinePassword(ctx, cfg, nextName, plan.Username.ValueString(), password.ValueString()); err != nil {
+			diags.AddError("failed to set password", err.Error())
+			discardNext()
+			return diags
+		}
+	}
+
+	keys := listToStrings(ctx, plan.AuthorizedKeys, &diags)
+	if err := syncAuthorizedKeys(ctx, cfg, nextName, plan.Username.ValueString(), keys, nil); err != nil {
+		diags.AddError("failed to install SSH keys", err.Error())
+		discardNext()
+		return diags
+	}
+
+	// The new machine still has its temporary name; the old one may be live under newName
+	if err := waitReadiness(ctx, readinessProbes(cfg, nextName, plan)); err != nil {
+		diags.AddError("new machine did not become ready", err.Error()+"\nThe new machine was deleted; the current machine was not changed.")
+		discardNext()
+		return diags
+	}
+
+	if state.BackupOnDestroy != nil {
+		dest, err := backupMachine(ctx, cfg, oldName, state.BackupOnDestroy)
+		if dest == "" {
+			diags.AddError("failed to back up machine", err.Error()+"\nThe new machine was deleted; the current machine was not changed.")
+			discardNext()
+			return diags
+		}
+		if err != nil {
+			diags.AddWarning("failed to prune backups", err.Error())
+		}
+	}
+
+	// Swap: move the old machine aside, then the new one into place
+	prevName, err := blueGreenName(oldName, "prev")
+	if err != nil {
+		diags.AddError("failed to generate machine name", err.Error())
+		discardNext()
+		return diags
+	}
+	if _, stderr, err := runOrb(ctx, cfg.OrbPath, "rename", oldName, prevName); err != nil {
+		diags.AddError("failed to rename machine", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		discardNext()
+		return diags
+	}
+	if _, stderr, err := runOrb(ctx, cfg.OrbPath, "rename", nextName, newName); err != nil {
+		diags.AddError("failed to rename machine", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		if _, stderr, err := runOrb(cleanupCtx, cfg.OrbPath, "rename", prevName, oldName); err != nil {
+			diags.AddError("failed to roll back", fmt.Sprintf("The current machine is now named %q; rename it back to %q manually. orb error: %s", prevName, oldName, strings.TrimSpace(stderr)))
+			return diags
+		}
+		discardNext()
+		return diags
+	}
+	tflog.Info(ctx, "blue/green: swapped machines", map[string]any{"machine": newName, "previous": prevName})
+
+	// Ownership follows the new machine
+	owner, err := currentOwner(model.CreatedAt.ValueString())
+	if err == nil {
+		err = claimMachine(newName, owner)
+	}
+	if err != nil {
+		diags.AddWarning("failed to record machine ownership", err.Error())
+	} else {
+		plan.OwnershipToken = types.StringValue(owner.Token)
+		if newName != oldName {
+			_ = releaseMachine(oldName, state.OwnershipToken.ValueString())
+		}
+	}
+	if plan.OwnershipToken.IsUnknown() {
+		plan.OwnershipToken = types.StringNull()
+	}
+
+	if plan.ResolvedImage.IsNull() || plan.ResolvedImage.IsUnknown() {
+		plan.ResolvedImage = types.StringNull()
+		if image, err := machineOSImage(ctx, cfg, newName); err == nil {
+			plan.ResolvedImage = types.StringValue(image)
+		}
+	}
+
+	// The default setting stayed with the old machine
+	if plan.DefaultMachine.ValueBool() {
+		if _, stderr, err := runOrb(ctx, cfg.OrbPath, "default", newName); err != nil {
+			diags.AddWarning("failed to set default machine", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		}
+	}
+
+	// Retire the old machine
+	if state.GracefulShutdown.ValueBool() {
+		if err := stopMachineGracefully(ctx, cfg, prevName, parseTimeout(cfg.DeleteTimeout, 5*time.Minute)); err != nil {
+			tflog.Warn(ctx, "graceful shutdown failed, deleting anyway", map[string]any{"machine": prevName, "error": err.Error()})
+		}
+	}
+	if _, stderr, err := runOrb(cleanupCtx, cfg.OrbPath, "delete", prevName); err != nil {
+		diags.AddWarning("failed to delete previous machine", fmt.Sprintf("The new machine is in place, but the previous one was kept as %q; delete it manually. orb error: %s", prevName, strings.TrimSpace(stderr)))
+	}
+	return diags
+}
@@ -0,0 +1,571 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"gopkg.in/yaml.v3"
+)
+
+const cloudConfigHeader = "#cloud-config"
+
+// CloudConfigModel maps the cloud_config block of orbstack_machine.
+type CloudConfigModel struct {
+	Packages       types.List                  `tfsdk:"packages"`
+	PackageUpdate  types.Bool                  `tfsdk:"package_update"`
+	PackageUpgrade types.Bool                  `tfsdk:"package_upgrade"`
+	Runcmd         types.List                  `tfsdk:"runcmd"`
+	Timezone       types.String                `tfsdk:"timezone"`
+	WriteFiles     []CloudConfigWriteFileModel `tfsdk:"write_files"`
+	Users          []CloudConfigUserModel      `tfsdk:"users"`
+	AptSources     []CloudConfigAptSourceModel `tfsdk:"apt_sources"`
+}
+
+type CloudConfigWriteFileModel struct {
+	Path        types.String `tfsdk:"path"`
+	Content     types.String `tfsdk:"content"`
+	Permissions types.String `tfsdk:"permissions"`
+	Owner       types.String `tfsdk:"owner"`
+	Append      types.Bool   `tfsdk:"append"`
+}
+
+type CloudConfigUserModel struct {
+	Name              types.String `tfsdk:"name"`
+	Groups            types.List   `tfsdk:"groups"`
+	Shell             types.String `tfsdk:"shell"`
+	Sudo              types.String `tfsdk:"sudo"`
+	LockPasswd        types.Bool   `tfsdk:"lock_passwd"`
+	SSHAuthorizedKeys types.List   `tfsdk:"ssh_authorized_keys"`
+}
+
+type CloudConfigAptSourceModel struct {
+	Name   types.String `tfsdk:"name"`
+	Source types.String `tfsdk:"source"`
+	Key    types.String `tfsdk:"key"`
+	KeyID  types.String `tfsdk:"keyid"`
+}
+
+// renderCloudConfig converts the cloud_config block into a cloud-init document tree.
+func renderCloudConfig(ctx context.Context, cc *CloudConfigModel) (map[string]any, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	doc := make(map[string]any)
+	if cc == nil {
+		return doc, diags
+	}
+
+	if pkgs := listToStrings(ctx, cc.Packages, &diags); len(pkgs) > 0 {
+		doc["packages"] = pkgs
+	}
+	if !cc.PackageUpdate.IsNull() && !cc.PackageUpdate.IsUnknown() {
+		doc["package_update"] = cc.PackageUpdate.ValueBool()
+	}
+	if !cc.PackageUpgrade.IsNull() && !cc.PackageUpgrade.IsUnknown() {
+		doc["package_upgrade"] = cc.PackageUpgrade.ValueBool()
+	}
+	if cmds := listToStrings(ctx, cc.Runcmd, &diags); len(cmds) > 0 {
+		doc["runcmd"] = cmds
+	}
+	if v := strings.TrimSpace(cc.Timezone.ValueString()); v != "" {
+		doc["timezone"] = v
+	}
+
+	if len(cc.WriteFiles) > 0 {
+		files := make([]any, 0, len(cc.WriteFiles))
+		for _, f := range cc.WriteFiles {
+			entry := map[string]any{
+				"path":    f.Path.ValueString(),
+				"content": f.Content.ValueString(),
+			}
+			if v := f.Permissions.ValueString(); v != "" {
+				entry["permissions"] = v
+			}
+			if v := f.Owner.ValueString(); v != "" {
+				entry["owner"] = v
+			}
+			if f.Append.ValueBool() {
+				entry["append"] = true
+			}
+			files = append(files, entry)
+		}
+		doc["write_files"] = files
+	}
+
+	if len(cc.Users) > 0 {
+		users := make([]any, 0, len(cc.Users))
+		for _, u := range cc.Users {
+			entry := map[string]any{"name": u.Name.ValueString()}
+			if groups := listToStrings(ctx, u.Groups, &diags); len(groups) > 0 {
+				entry["groups"] = strings.Join(groups, ", ")
+			}
+			if v := u.Shell.ValueString(); v != "" {
+				entry["shell"] = v
+			}
+			if v := u.Sudo.ValueString(); v != "" {
+				entry["sudo"] = v
+			}
+			if !u.LockPasswd.IsNull() && !u.LockPasswd.IsUnknown() {
+				entry["lock_passwd"] = u.LockPasswd.ValueBool()
+			}
+			if keys := listToStrings(ctx, u.SSHAuthorizedKeys, &diags); len(keys) > 0 {
+				entry["ssh_authorized_keys"] = keys
+			}
+			users = append(users, entry)
+		}
+		doc["users"] = users
+	}
+
+	if len(cc.AptSources) > 0 {
+		sources := make(map[string]any, len(cc.AptSources))
+		for _, s := range cc.AptSources {
+			entry := map[string]any{"source": s.Source.ValueString()}
+			if v := s.Key.ValueString(); v != "" {
+				entry["key"] = v
+			}
+			if v := s.KeyID.ValueString(); v != "" {
+				entry["keyid"] = v
+			}
+			sources[s.Name.ValueString()] = entry
+		}
+		doc["apt"] = map[string]any{"sources": sources}
+	}
+
+	return doc, diags
+}
+
+// mergeCloudConfig merges a rendered cloud_config tree into raw user data.
+// Lists are concatenated (raw entries first), maps are merged recursively and
+// scalars from the structured block win. Raw user data in another format is
+// combined with the rendered cloud-config in a MIME multi-part document.
+func mergeCloudConfig(raw string, structured map[string]any) (string, error) {
+	doc := make(map[string]any)
+	trimmed := strings.TrimSpace(raw)
+	if trimmed != "" && !isCloudConfig(trimmed) {
+		rendered, err := renderCloudConfigDocument(doc, structured)
+		if err != nil {
+			return "", err
+		}
+		return multipartUserData(raw, rendered), nil
+	}
+	if trimmed != "" {
+		if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+			return "", fmt.Errorf("failed to parse cloud-init user data: %w", err)
+		}
+		if doc == nil {
+			doc = make(map[string]any)
+		}
+	}
+	return renderCloudConfigDocument(doc, structured)
+}
+
+// renderCloudConfigDocument merges structured into doc and renders the result
+// as a #cloud-config document.
+func renderCloudConfigDocument(doc, structured map[string]any) (string, error) {
+	mergeCloudConfigMaps(doc, structured)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return "", fmt.Errorf("failed to render cloud-config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to render cloud-config: %w", err)
+	}
+	return cloudConfigHeader + "\n" + buf.String(), nil
+}
+
+// isCloudConfig reports whether user data starts with the #cloud-config header
+// itself rather than a longer header such as #cloud-config-archive.
+func isCloudConfig(content string) bool {
+	first, _, _ := strings.Cut(content, "\n")
+	return strings.TrimRight(first, " \t\r") == cloudConfigHeader
+}
+
+// userDataContentTypes maps user data headers to the MIME type cloud-init
+// expects for them in a multi-part document. Longer headers come first.
+var userDataContentTypes = []struct {
+	header      string
+	contentType string
+}{
+	{"#!", "text/x-shellscript"},
+	{"#include-once", "text/x-include-once-url"},
+	{"#include", "text/x-include-url"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#part-handler", "text/part-handler"},
+	{"#cloud-config-archive", "text/cloud-config-archive"},
+	{"#cloud-config-jsonp", "text/cloud-config-jsonp"},
+	{"## template: jinja", "text/jinja2"},
+}
+
+// multipartUserData builds a MIME multi-part user data document from raw user
+// data followed by a cloud-config document. Raw user data that already is a
+// MIME document is nested as is; cloud-init walks nested multi-part documents.
+func multipartUserData(raw, cloudConfig string) string {
+	// Derive the boundary from the content so the document is reproducible
+	sum := sha256.Sum256([]byte(raw + cloudConfig))
+	boundary := "==orbstack-" + hex.EncodeToString(sum[:8]) + "=="
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=\"%s\"\nMIME-Version: 1.0\n\n", boundary)
+
+	fmt.Fprintf(&b, "--%s\n", boundary)
+	trimmed := strings.TrimLeft(raw, " \t\r\n")
+	if strings.HasPrefix(trimmed, "Content-Type:") || strings.HasPrefix(trimmed, "MIME-Version:") {
+		b.WriteString(trimmed)
+	} else {
+		contentType := "text/plain"
+		for _, t := range userDataContentTypes {
+			if strings.HasPrefix(trimmed, t.header) {
+				contentType = t.contentType
+				break
+			}
+		}
+		writeUserDataPartHeader(&b, contentType, "user-data", "")
+		b.WriteString(raw)
+	}
+	if !strings.HasSuffix(raw, "\n") {
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "--%s\n", boundary)
+	writeUserDataPartHeader(&b, "text/cloud-config", "cloud-config.yaml", cloudConfigMergeType)
+	b.WriteString(cloudConfig)
+	fmt.Fprintf(&b, "--%s--\n", boundary)
+	return b.String()
+}
+
+// cloudConfigMergeType makes cloud-init append the lists of the rendered
+// cloud-config part to those of earlier parts instead of replacing them.
+const cloudConfigMergeType = "list(append)+dict(recurse_array)+str()"
+
+// writeUserDataPartHeader writes the headers of a multi-part user data part.
+func writeUserDataPartHeader(b *strings.Builder, contentType, filename, mergeType string) {
+	fmt.Fprintf(b, "Content-Type: %s; charset=\"utf-8\"\nMIME-Version: 1.0\n", contentType)
+	if mergeType != "" {
+		fmt.Fprintf(b, "Merge-Type: %s\n", mergeType)
+	}
+	fmt.Fprintf(b, "Content-Disposition: attachment; filename=\"%s\"\n\n", filename)
+}
+
+func mergeCloudConfigMaps(dst, src map[string]any) {
+	for k, v := range src {
+		existing, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		switch sv := v.(type) {
+		case []any:
+			if dv, ok := existing.([]any); ok {
+				dst[k] = append(dv, sv...)
+				continue
+			}
+		case []string:
+			if dv, ok := existing.([]any); ok {
+				for _, s := range sv {
+					dv = append(dv, s)
+				}
+				dst[k] = dv
+				continue
+			}
+		case map[string]any:
+			if dv, ok := existing.(map[string]any); ok {
+				mergeCloudConfigMaps(dv, sv)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// prepareCloudInit resolves the user data for orb create -c. It returns the path
+// to pass (empty when no cloud-init is configured) and a cleanup function.
+func prepareCloudInit(ctx context.Context, plan *MachineModel) (string, func(), diag.Diagnostics) {
+	var diags diag.Diagnostics
+	cleanup := func() {}
+
+	raw := plan.CloudInit.ValueString()
+
+	// cloud_init and cloud_init_file are mutually exclusive (see ValidateConfig)
+	if f := strings.TrimSpace(plan.CloudInitFile.ValueString()); f != "" {
+		// ensure the file exists and pass absolute path
+		if _, err := os.Stat(f); err != nil {
+			diags.AddAttributeError(path.Root("cloud_init_file"), "cloud_init_file not found", err.Error())
+			return "", cleanup, diags
+		}
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			diags.AddAttributeError(path.Root("cloud_init_file"), "failed to resolve cloud_init_file path", err.Error())
+			return "", cleanup, diags
+		}
+		if plan.CloudConfig == nil {
+			return abs, cleanup, diags
+		}
+		content, err := os.ReadFile(abs)
+		if err != nil {
+			diags.AddAttributeError(path.Root("cloud_init_file"), "failed to read cloud_init_file", err.Error())
+			return "", cleanup, diags
+		}
+		raw = string(content)
+	}
+
+	if plan.CloudConfig != nil {
+		structured, d := renderCloudConfig(ctx, plan.CloudConfig)
+		diags.Append(d...)
+		if diags.HasError() {
+			return "", cleanup, diags
+		}
+		merged, err := mergeCloudConfig(raw, structured)
+		if err != nil {
+			diags.AddAttributeError(path.Root("cloud_config"), "failed to render cloud_config", err.Error())
+			return "", cleanup, diags
+		}
+		raw = merged
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		return "", cleanup, diags
+	}
+
+	name, err := writeCloudInitTempFile(raw)
+	if err != nil {
+		diags.AddError("failed to write cloud-init temp file", err.Error())
+		return "", cleanup, diags
+	}
+	return name, func() { os.Remove(name) }, diags
+}
+
+// writeCloudInitTempFile stores user data in a temp file suitable for orb create -c.
+func writeCloudInitTempFile(content string) (string, error) {
+	tmpFile, err := os.CreateTemp("", "orbstack-cloudinit-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
+
+// listToStrings converts a list of strings, skipping null and unknown lists.
+func listToStrings(ctx context.Context, l types.List, diags *diag.Diagnostics) []string {
+	if l.IsNull() || l.IsUnknown() {
+		return nil
+	}
+	var out []string
+	diags.Append(l.ElementsAs(ctx, &out, false)...)
+	return out
+}
+
+// userDataHeaders lists the non cloud-config user data formats cloud-init accepts.
+var userDataHeaders = []string{
+	"#!",
+	"#include",
+	"#cloud-boothook",
+	"#part-handler",
+	"#cloud-config-archive",
+	"#cloud-config-jsonp",
+	"Content-Type:",
+	"MIME-Version:",
+}
+
+// knownCloudConfigKeys lists the top-level keys handled by cloud-init modules.
+var knownCloudConfigKeys = map[string]struct{}{}
+
+func init() {
+	for _, k := range []string{
+		"ansible", "apk_repos", "apt", "apt_pipelining", "autoinstall", "bootcmd",
+		"byobu_by_default", "ca_certs", "ca-certs", "chef", "chpasswd",
+		"cloud_config_modules", "cloud_final_modules", "cloud_init_modules",
+		"create_hostname_file", "datasource", "device_aliases", "disable_ec2_metadata",
+		"disable_root", "disable_root_opts", "disk_setup", "drivers", "fan",
+		"final_message", "fqdn", "fs_setup", "groups", "growpart", "grub_dpkg",
+		"grub-dpkg", "hostname", "keyboard", "landscape", "locale", "locale_configfile",
+		"lxd", "manage_etc_hosts", "manage_resolv_conf", "mcollective", "merge_how",
+		"merge_type", "mount_default_fields", "mounts", "no_ssh_fingerprints", "ntp",
+		"output", "package_reboot_if_required", "package_update", "package_upgrade",
+		"packages", "password", "phone_home", "power_state", "prefer_fqdn_over_hostname",
+		"preserve_hostname", "puppet", "random_seed", "reporting", "resize_rootfs",
+		"resolv_conf", "rh_subscription", "rsyslog", "runcmd", "salt_minion", "snap",
+		"spacewalk", "ssh", "ssh_authorized_keys", "ssh_deletekeys",
+		"ssh_fp_console_blacklist", "ssh_genkeytypes", "ssh_import_id",
+		"ssh_key_console_blacklist", "ssh_keys", "ssh_publish_hostkeys", "ssh_pwauth",
+		"ssh_quiet_keygen", "swap", "system_info", "timezone", "ubuntu_advantage",
+		"ubuntu_pro", "updates", "user", "users", "vendor_data", "wireguard",
+		"write_files", "yum_repo_dir", "yum_repos", "zypper",
+	} {
+		knownCloudConfigKeys[k] = struct{}{}
+	}
+}
+
+var yamlErrorPrefix = regexp.MustCompile(`^yaml: (?:unmarshal errors:\s*)?(?:line \d+: )?`)
+
+// yamlErrorLine returns the line at which the document stops being valid YAML.
+// yaml.v3 reports the line of the enclosing block rather than the offending
+// one, so growing prefixes of the document are parsed instead. A prefix that
+// only fails because it ends inside a quoted scalar or flow collection is
+// closed and parsed again; when the whole document fails that way, the line
+// that opened the unclosed construct is returned.
+func yamlErrorLine(body string) int {
+	lines := strings.Split(body, "\n")
+	for i := 1; i <= len(lines); i++ {
+		prefix := strings.Join(lines[:i], "\n")
+		if yamlParses(prefix) {
+			continue
+		}
+		closers, inComment, openLine := yamlUnclosed(prefix)
+		if closers == "" {
+			return i
+		}
+		sep := " "
+		if inComment {
+			sep = "\n"
+		}
+		if !yamlParses(prefix + sep + closers) {
+			return i
+		}
+		if i == len(lines) {
+			return openLine
+		}
+	}
+	return len(lines)
+}
+
+func yamlParses(content string) bool {
+	var doc any
+	return yaml.Unmarshal([]byte(content), &doc) == nil
+}
+
+// yamlUnclosed scans content for quoted scalars and flow collections that are
+// still open at its end. It returns the text that closes them, whether content
+// ends in a comment and the 1-based line of the outermost open construct.
+func yamlUnclosed(content string) (string, bool, int) {
+	var stack []byte
+	var lines []int
+	line := 1
+	var quote byte
+	inComment := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if c == '\n' {
+			line++
+			inComment = false
+			continue
+		}
+		switch {
+		case inComment:
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '#' && (i == 0 || content[i-1] == ' ' || content[i-1] == '\t' || content[i-1] == '\n'):
+			inComment = true
+		case c == '"' || c == '\'':
+			// Quotes only start a scalar at its beginning
+			if prev := strings.TrimRight(content[:i], " \t"); prev == "" || strings.ContainsRune(":-[{,\n", rune(prev[len(prev)-1])) {
+				quote = c
+				stack = append(stack, c)
+				lines = append(lines, line)
+			}
+		case c == '[' || c == '{':
+			stack = append(stack, c)
+			lines = append(lines, line)
+		case c == ']' || c == '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+				lines = lines[:len(lines)-1]
+			}
+		}
+		if quote == 0 && len(stack) > 0 && (stack[len(stack)-1] == '"' || stack[len(stack)-1] == '\'') {
+			// The quoted scalar just closed
+			stack = stack[:len(stack)-1]
+			lines = lines[:len(lines)-1]
+		}
+	}
+	if len(stack) == 0 {
+		return "", inComment, 0
+	}
+
+	var closers strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i] {
+		case '[':
+			closers.WriteByte(']')
+		case '{':
+			closers.WriteByte('}')
+		default:
+			closers.WriteByte(stack[i])
+		}
+	}
+	return closers.String(), inComment, lines[0]
+}
+
+// validateCloudInit checks user data the way cloud-init classifies it. It returns
+// warnings for unknown top-level keys and an error for content cloud-init rejects.
+func validateCloudInit(content string) ([]string, error) {
+	body := content
+	first, rest, _ := strings.Cut(body, "\n")
+	// Jinja templates carry the real header on the second line
+	if strings.HasPrefix(strings.TrimSpace(first), "## template:") {
+		first, _, _ = strings.Cut(rest, "\n")
+	}
+	first = strings.TrimRight(first, " \t\r")
+
+	if first != cloudConfigHeader {
+		for _, h := range userDataHeaders {
+			if strings.HasPrefix(first, h) {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("user data must start with %s, a #! script or another format supported by cloud-init (got %q)", cloudConfigHeader, first)
+	}
+
+	var doc any
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		msg := yamlErrorPrefix.ReplaceAllString(err.Error(), "")
+		return nil, fmt.Errorf("invalid cloud-config YAML at line %d: %s", yamlErrorLine(body), msg)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid cloud-config: top level must be a mapping")
+	}
+
+	var warnings []string
+	keys := make([]string, 0, len(top))
+	for k := range top {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := knownCloudConfigKeys[k]; !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown top-level cloud-config key %q will be ignored by cloud-init", k))
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,90 @@
+package provider
+
+import "testing"
+
+func TestYAMLErrorLine(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{
+			name: "mis-indented key",
+			body: "#cloud-config\n" +
+				"package_update: true\n" +
+				"packages:\n" +
+				"  - git\n" +
+				"write_files:\n" +
+				"  - path: /etc/motd\n" +
+				"   content: hello\n",
+			want: 7,
+		},
+		{
+			name: "mis-indented list item",
+			body: "#cloud-config\n" +
+				"packages:\n" +
+				"  - git\n" +
+				" - vim\n",
+			want: 4,
+		},
+		{
+			name: "block entry inside a flow sequence",
+			body: "#cloud-config\n" +
+				"timezone: UTC\n" +
+				"packages: [git,\n" +
+				"  vim,\n" +
+				"  - curl\n" +
+				"runcmd:\n" +
+				"  - echo hi\n",
+			want: 5,
+		},
+		{
+			name: "flow sequence never closed",
+			body: "#cloud-config\n" +
+				"timezone: UTC\n" +
+				"packages: [git,\n" +
+				"  vim\n",
+			want: 3,
+		},
+		{
+			name: "flow mapping never closed",
+			body: "#cloud-config\n" +
+				"apt: {preserve_sources_list: true,\n" +
+				"  conf: x\n",
+			want: 2,
+		},
+		{
+			name: "double-quoted scalar never closed",
+			body: "#cloud-config\n" +
+				"timezone: UTC\n" +
+				"final_message: \"done\n",
+			want: 3,
+		},
+		{
+			name: "tab indentation",
+			body: "#cloud-config\n" +
+				"runcmd:\n" +
+				"\t- echo hi\n",
+			want: 3,
+		},
+		{
+			name: "duplicate key",
+			body: "#cloud-config\n" +
+				"timezone: UTC\n" +
+				"packages: [git]\n" +
+				"timezone: CET\n",
+			want: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if yamlParses(tt.body) {
+				t.Fatalf("test document parses")
+			}
+			if got := yamlErrorLine(tt.body); got != tt.want {
+				t.Errorf("yamlErrorLine() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,14 +23,20 @@ type MachineDataSource struct {
 }
 
 type MachineDataSourceModel struct {
-	Name           types.String `tfsdk:"name"`
-	ID             types.String `tfsdk:"id"`
-	IPAddress      types.String `tfsdk:"ip_address"`
-	Status         types.String `tfsdk:"status"`
-	SSHHost        types.String `tfsdk:"ssh_host"`
-	SSHPort        types.Int64  `tfsdk:"ssh_port"`
-	CreatedAt      types.String `tfsdk:"created_at"`
-	DefaultMachine types.Bool   `tfsdk:"default_machine"`
+	Name              types.String `tfsdk:"name"`
+	ID                types.String `tfsdk:"id"`
+	IPAddress         types.String `tfsdk:"ip_address"`
+	IPv6Address       types.String `tfsdk:"ipv6_address"`
+	Addresses         types.List   `tfsdk:"addresses"`
+	FQDN              types.String `tfsdk:"fqdn"`
+	Status            types.String `tfsdk:"status"`
+	SSHUser           types.String `tfsdk:"ssh_user"`
+	SSHHost           types.String `tfsdk:"ssh_host"`
+	SSHPort           types.Int64  `tfsdk:"ssh_port"`
+	SSHPrivateKeyPath types.String `tfsdk:"ssh_private_key_path"`
+	SSHCommand        types.String `tfsdk:"ssh_command"`
+	CreatedAt         types.String `tfsdk:"created_at"`
+	DefaultMachine    types.Bool   `tfsdk:"default_machine"`
 }
 
 func (d *MachineDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -54,17 +60,42 @@ func (d *MachineDataSource) Schema(_ context.Context, _ datasource.SchemaRequest
 				Computed:    true,
 				Description: "Machine IP address.",
 			},
+			"ipv6_address": schema.StringAttribute{
+				Computed:    true,
+				Description: "Machine IPv6 address.",
+			},
+			"addresses": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "All machine IP addresses (IPv4 first).",
+			},
+			"fqdn": schema.StringAttribute{
+				Computed:    true,
+				Description: "DNS name of the machine (name.orb.local).",
+			},
 			"status": schema.StringAttribute{
 				Computed:    true,
 				Description: "Current status.",
 			},
+			"ssh_user": schema.StringAttribute{
+				Computed:    true,
+				Description: "SSH login for OrbStack's SSH proxy (default user).",
+			},
 			"ssh_host": schema.StringAttribute{
 				Computed:    true,
-				Description: "SSH host.",
+				Description: "SSH host of OrbStack's SSH proxy.",
 			},
 			"ssh_port": schema.Int64Attribute{
 				Computed:    true,
-				Description: "SSH port.",
+				Description: "SSH port of OrbStack's SSH proxy.",
+			},
+			"ssh_private_key_path": schema.StringAttribute{
+				Computed:    true,
+				Description: "Private key accepted by OrbStack's SSH proxy.",
+			},
+			"ssh_command": schema.StringAttribute{
+				Computed:    true,
+				Description: "Ready-to-use ssh command line for the machine.",
 			},
 			"created_at": schema.StringAttribute{
 				Computed:    true,
@@ -116,9 +147,15 @@ func (d *MachineDataSource) Read(ctx context.Context, req datasource.ReadRequest
 
 	data.ID = types.StringValue(name)
 	data.IPAddress = model.IPAddress
+	data.IPv6Address = model.IPv6Address
+	data.Addresses = model.Addresses
+	data.FQDN = model.FQDN
 	data.Status = model.Status
+	data.SSHUser = model.SSHUser
 	data.SSHHost = model.SSHHost
 	data.SSHPort = model.SSHPort
+	data.SSHPrivateKeyPath = model.SSHPrivateKeyPath
+	data.SSHCommand = model.SSHCommand
 	data.CreatedAt = model.CreatedAt
 
 	// Check if this machine is the current default
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultMachineClaims records which machine each resource wants as OrbStack's
+// default. Terraform plans every resource of a configuration through the same
+// provider instance, so conflicting claims can be reported at plan time.
+type defaultMachineClaims struct {
+	mu     sync.Mutex
+	claims map[string]defaultMachineClaim // resource key -> claim
+}
+
+// defaultMachineClaim is the machine a resource wants as the default, with a
+// description of the resource for error messages.
+type defaultMachineClaim struct {
+	claimant string
+	machine  string
+}
+
+func newDefaultMachineClaims() *defaultMachineClaims {
+	return &defaultMachineClaims{claims: make(map[string]defaultMachineClaim)}
+}
+
+// claimDefaultMachine records that the resource identified by key wants machine
+// as the default, replacing its earlier claim, and fails when another resource
+// wants a different machine. The key must stay the same across plans of one
+// resource, e.g. its state ID, so a rename or a generated name is not reported
+// as a conflict with itself.
+func (c *ClientConfig) claimDefaultMachine(key, claimant, machine string) error {
+	if c == nil || c.defaultClaims == nil {
+		return nil
+	}
+	d := c.defaultClaims
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var others []string
+	for other, claim := range d.claims {
+		if other != key && claim.machine != machine {
+			others = append(others, fmt.Sprintf("%s (machine %q)", claim.claimant, claim.machine))
+		}
+	}
+	if len(others) > 0 {
+		sort.Strings(others)
+		return fmt.Errorf("%s makes %q the default machine, which conflicts with %s. OrbStack has a single default machine; set default_machine = true on one machine only, or use orbstack_default_machine", claimant, machine, strings.Join(others, ", "))
+	}
+	d.claims[key] = defaultMachineClaim{claimant: claimant, machine: machine}
+	return nil
+}
+
+// releaseDefaultMachine drops the claim of the resource identified by key.
+func (c *ClientConfig) releaseDefaultMachine(key string) {
+	if c == nil || c.defaultClaims == nil {
+		return
+	}
+	c.defaultClaims.mu.Lock()
+	defer c.defaultClaims.mu.Unlock()
+	delete(c.defaultClaims.claims, key)
+}
+
+// currentDefaultMachine returns the name of OrbStack's default machine, or ""
+// when none is set.
+func currentDefaultMachine(ctx context.Context, cfg *ClientConfig) (string, error) {
+	out, stderr, err := runOrb(ctx, cfg.OrbPath, "default")
+	if err != nil {
+		return "", fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+	name := strings.TrimSpace(out)
+	if name == "none" {
+		return "", nil
+	}
+	return name, nil
+}
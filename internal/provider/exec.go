@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // ClientConfig holds provider runtime configuration.
@@ -15,11 +16,23 @@ type ClientConfig struct {
 	DefaultSSHKeyPath string
 	CreateTimeout     string
 	DeleteTimeout     string
+
+	// Default machine requested by each resource during this plan
+	defaultClaims *defaultMachineClaims
 }
 
 // runOrb runs orb with arguments and returns stdout as string.
 func runOrb(ctx context.Context, orbPath string, args ...string) (string, string, error) {
+	return runOrbInput(ctx, orbPath, "", args...)
+}
+
+// runOrbInput runs orb like runOrb, feeding stdin to the command. Use it for
+// secrets, which must not appear in the process list.
+func runOrbInput(ctx context.Context, orbPath, stdin string, args ...string) (string, string, error) {
 	cmd := exec.CommandContext(ctx, orbPath, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -29,3 +42,32 @@ func runOrb(ctx context.Context, orbPath string, args ...string) (string, string
 	return stdout.String(), stderr.String(), nil
 }
 
+// runInMachine runs a shell script inside a machine via orb run, as user when set.
+func runInMachine(ctx context.Context, cfg *ClientConfig, machine, user, script string) (string, string, error) {
+	args := []string{"run", "-m", machine}
+	if user != "" {
+		args = append(args, "-u", user)
+	}
+	args = append(args, "sh", "-c", script)
+	return runOrb(ctx, cfg.OrbPath, args...)
+}
+
+// parseTimeout parses a duration such as 5m, falling back to def when empty or invalid.
+func parseTimeout(s string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// shellQuote quotes s for POSIX shells when it contains special characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '/' || r == '.' || r == '-' || r == '_' || r == '@' || r == ':' || r == '=' || r == '+' || r == ',' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	hostArchOnce  sync.Once
+	hostArchValue string
+)
+
+// hostArch returns the CPU architecture of the Mac (amd64 or arm64). A provider
+// binary running under Rosetta reports amd64 in runtime.GOARCH, so macOS is
+// asked directly.
+func hostArch() string {
+	hostArchOnce.Do(func() {
+		hostArchValue = normalizeArch(runtime.GOARCH)
+		if runtime.GOOS != "darwin" {
+			return
+		}
+		out, err := exec.Command("sysctl", "-n", "hw.optional.arm64").Output()
+		if err == nil && strings.TrimSpace(string(out)) == "1" {
+			hostArchValue = "arm64"
+		}
+	})
+	return hostArchValue
+}
+
+// normalizeArch maps kernel architecture names to the names orb uses.
+func normalizeArch(arch string) string {
+	switch arch = strings.ToLower(strings.TrimSpace(arch)); arch {
+	case "x86_64", "x86-64", "x64":
+		return "amd64"
+	case "aarch64", "arm64e":
+		return "arm64"
+	}
+	return arch
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// importSourceConflicts keeps image_source and its deprecated alias
+// source_archive apart from each other and from every other way to create a
+// machine.
+var importSourceConflicts = stringvalidator.ConflictsWith(
+	path.MatchRoot("clone_from"),
+	path.MatchRoot("source_archive"),
+	path.MatchRoot("image_source"),
+	path.MatchRoot("image"),
+	path.MatchRoot("arch"),
+	path.MatchRoot("username"),
+	path.MatchRoot("cloud_init"),
+	path.MatchRoot("cloud_init_file"),
+	path.MatchRoot("cloud_config"),
+	path.MatchRoot("validate_image"),
+)
+
+// importSource returns the attribute and trimmed path of the file a machine is
+// imported from: image_source, or source_archive which behaves the same.
+func importSource(m *MachineModel) (path.Path, string) {
+	if source := strings.TrimSpace(m.SourceArchive.ValueString()); source != "" {
+		return path.Root("source_archive"), source
+	}
+	return path.Root("image_source"), strings.TrimSpace(m.ImageSource.ValueString())
+}
+
+// imageSourceChecksum returns the absolute path and SHA-256 checksum of an
+// image_source file.
+func imageSourceChecksum(source string) (string, string, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(source))
+	if err != nil {
+		return "", "", err
+	}
+	sum, _, err := fileSHA256(abs)
+	if err != nil {
+		return abs, "", err
+	}
+	return abs, sum, nil
+}
+
+// imageSourcePrivateKey is the private state key holding the imageSourceStamp
+// of the file image_source_sha256 was computed from.
+const imageSourcePrivateKey = "image_source"
+
+// imageSourceStamp identifies a checksummed image_source file by path, size
+// and modification time, so an unchanged file is not hashed on every plan.
+type imageSourceStamp struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	SHA256  string `json:"sha256"`
+}
+
+// statImageSource returns the stamp of an image_source file without its checksum.
+func statImageSource(source string) (imageSourceStamp, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(source))
+	if err != nil {
+		return imageSourceStamp{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return imageSourceStamp{Path: abs}, err
+	}
+	return imageSourceStamp{Path: abs, Size: info.Size(), ModTime: info.ModTime().UnixNano()}, nil
+}
+
+// privateStateSetter is implemented by the private state of resource responses.
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// storeImageSourceStamp records the stamp of the file a checksum was computed
+// from in private state.
+func storeImageSourceStamp(ctx context.Context, private privateStateSetter, stamp imageSourceStamp) diag.Diagnostics {
+	data, err := json.Marshal(stamp)
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError("failed to record image_source", err.Error())
+		return diags
+	}
+	return private.SetKey(ctx, imageSourcePrivateKey, data)
+}
+
+// planImageSource checksums image_source or source_archive at plan time. A
+// configured image_source_sha256 must match the file; a file whose checksum
+// differs from the one recorded in state replaces the machine. The file is
+// only hashed again when its path, size or modification time differ from the
+// ones recorded with the checksum in private state.
+func (r *MachineResource) planImageSource(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var source, archive, expected, prior types.String
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("image_source"), &source)...)
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("source_archive"), &archive)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("image_source_sha256"), &expected)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("image_source_sha256"), &prior)...)
+	}
+	attr := path.Root("image_source")
+	if !archive.IsNull() {
+		attr, source = path.Root("source_archive"), archive
+	}
+	if resp.Diagnostics.HasError() || source.IsUnknown() || expected.IsUnknown() {
+		return
+	}
+	if source.IsNull() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("image_source_sha256"), types.StringNull())...)
+		return
+	}
+
+	stamp, err := statImageSource(source.ValueString())
+	if err == nil && !prior.IsNull() && !prior.IsUnknown() {
+		// Reuse the checksum in state while the file is unchanged since it was computed
+		var recorded imageSourceStamp
+		data, diags := req.Private.GetKey(ctx, imageSourcePrivateKey)
+		resp.Diagnostics.Append(diags...)
+		stamp.SHA256 = prior.ValueString()
+		if data == nil || json.Unmarshal(data, &recorded) != nil || recorded != stamp {
+			stamp.SHA256 = ""
+		}
+	}
+	if err == nil && stamp.SHA256 == "" {
+		stamp.SHA256, _, err = fileSHA256(stamp.Path)
+		if err == nil {
+			resp.Diagnostics.Append(storeImageSourceStamp(ctx, resp.Private, stamp)...)
+		}
+	}
+	abs, sum := stamp.Path, stamp.SHA256
+	if err != nil {
+		if req.State.Raw.IsNull() {
+			resp.Diagnostics.AddAttributeError(attr, attr.String()+" not readable", err.Error())
+			return
+		}
+		// The machine exists; a removed build artifact is no reason to replace it
+		resp.Diagnostics.AddAttributeWarning(attr, "cannot check "+attr.String()+" for changes", err.Error())
+		return
+	}
+
+	if !expected.IsNull() && expected.ValueString() != sum {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("image_source_sha256"),
+			attr.String()+" checksum mismatch",
+			fmt.Sprintf("%s has SHA-256 %s, expected %s.", abs, sum, expected.ValueString()),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("image_source_sha256"), types.StringValue(sum))...)
+	if !prior.IsNull() && !prior.IsUnknown() && prior.ValueString() != sum && !isBlueGreen(ctx, resp.Plan) {
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("image_source_sha256"))
+	}
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ownershipRegistryFile lives in the user's config directory and maps machine
+// names to the Terraform configuration that created them.
+const ownershipRegistryFile = "ownership.json"
+
+// ownershipMu serializes registry access within the provider process; a file
+// lock does the same across processes.
+var ownershipMu sync.Mutex
+
+// machineOwner is the ownership marker recorded for a machine.
+type machineOwner struct {
+	Token     string `json:"token"`
+	Workspace string `json:"workspace"`
+	Directory string `json:"directory"`
+	CreatedAt string `json:"created_at,omitempty"`
+	ClaimedAt string `json:"claimed_at"`
+}
+
+// String describes the owner for diagnostics.
+func (o machineOwner) String() string {
+	return fmt.Sprintf("workspace %q in %s", o.Workspace, o.Directory)
+}
+
+// currentOwner describes the running Terraform configuration. Providers do not
+// learn the resource address, so the working directory and workspace identify
+// the configuration instead.
+func currentOwner(createdAt string) (machineOwner, error) {
+	token, err := newOwnershipToken()
+	if err != nil {
+		return machineOwner{}, err
+	}
+	dir, _ := os.Getwd()
+	return machineOwner{
+		Token:     token,
+		Workspace: currentWorkspace(dir),
+		Directory: dir,
+		CreatedAt: createdAt,
+		ClaimedAt: time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// currentWorkspace returns TF_WORKSPACE, the workspace selected in dir, or default.
+func currentWorkspace(dir string) string {
+	if ws := strings.TrimSpace(os.Getenv("TF_WORKSPACE")); ws != "" {
+		return ws
+	}
+	dataDir := firstNonEmpty(os.Getenv("TF_DATA_DIR"), filepath.Join(dir, ".terraform"))
+	if b, err := os.ReadFile(filepath.Join(dataDir, "environment")); err == nil {
+		if ws := strings.TrimSpace(string(b)); ws != "" {
+			return ws
+		}
+	}
+	return "default"
+}
+
+func newOwnershipToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ownership token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ownershipRegistryPath returns the registry file path.
+func ownershipRegistryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "terraform-provider-orbstack", ownershipRegistryFile), nil
+}
+
+// readOwnershipRegistry parses the registry file; a missing file is empty.
+func readOwnershipRegistry(p string) (map[string]machineOwner, error) {
+	reg := make(map[string]machineOwner)
+	if b, err := os.ReadFile(p); err == nil && len(b) > 0 {
+		if err := json.Unmarshal(b, &reg); err != nil {
+			return nil, fmt.Errorf("ownership registry %s is corrupt: %w", p, err)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read ownership registry: %w", err)
+	}
+	return reg, nil
+}
+
+// updateOwnership runs fn on the registry under both locks and saves the result.
+func updateOwnership(fn func(reg map[string]machineOwner) error) error {
+	ownershipMu.Lock()
+	defer ownershipMu.Unlock()
+
+	p, err := ownershipRegistryPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(p), 0o700)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to locate ownership registry: %w", err)
+	}
+
+	lock, err := os.OpenFile(p+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to lock ownership registry: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock ownership registry: %w", err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	reg, err := readOwnershipRegistry(p)
+	if err != nil {
+		return err
+	}
+	if err := fn(reg); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write atomically so a crash never leaves a truncated registry
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write ownership registry: %w", err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("failed to write ownership registry: %w", err)
+	}
+	return nil
+}
+
+// lookupOwner returns the recorded owner of a machine, if any. It neither locks
+// nor rewrites the registry: writes replace the file atomically, so plans and
+// refreshes only read it.
+func lookupOwner(name string) (machineOwner, bool, error) {
+	p, err := ownershipRegistryPath()
+	if err != nil {
+		return machineOwner{}, false, fmt.Errorf("failed to locate ownership registry: %w", err)
+	}
+	reg, err := readOwnershipRegistry(p)
+	if err != nil {
+		return machineOwner{}, false, err
+	}
+	owner, ok := reg[name]
+	return owner, ok, nil
+}
+
+// claimMachine records owner as the owner of a machine, replacing any prior marker.
+func claimMachine(name string, owner machineOwner) error {
+	return updateOwnership(func(reg map[string]machineOwner) error {
+		reg[name] = owner
+		return nil
+	})
+}
+
+// renameOwnership moves a machine's marker to its new name.
+func renameOwnership(oldName, newName string) error {
+	return updateOwnership(func(reg map[string]machineOwner) error {
+		if owner, ok := reg[oldName]; ok {
+			delete(reg, oldName)
+			reg[newName] = owner
+		}
+		return nil
+	})
+}
+
+// releaseMachine removes the marker of a machine, but only when token owns it.
+func releaseMachine(name, token string) error {
+	return updateOwnership(func(reg map[string]machineOwner) error {
+		if owner, ok := reg[name]; ok && owner.Token == token {
+			delete(reg, name)
+		}
+		return nil
+	})
+}
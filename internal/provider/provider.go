@@ -51,11 +51,11 @@ func (p *OrbStackProvider) Schema(_ context.Context, _ provider.SchemaRequest, r
 			},
 			"default_user": schema.StringAttribute{
 				Optional:    true,
-				Description: "Default user for SSH metadata (read-only usage).",
+				Description: "Default username for machines that do not set username. Used for orb create -u, commands run inside machines and SSH attributes.",
 			},
 			"default_ssh_key_path": schema.StringAttribute{
 				Optional:    true,
-				Description: "Default SSH public key path for metadata/reporting.",
+				Description: "Default SSH public key installed in the authorized_keys of every orbstack_machine.",
 			},
 			"create_timeout": schema.StringAttribute{
 				Optional:    true,
@@ -83,6 +83,7 @@ func (p *OrbStackProvider) Configure(ctx context.Context, req provider.Configure
 		DefaultSSHKeyPath: stringOrDefault(data.DefaultSSHKeyPath, ""),
 		CreateTimeout:     stringOrDefault(data.CreateTimeout, "5m"),
 		DeleteTimeout:     stringOrDefault(data.DeleteTimeout, "5m"),
+		defaultClaims:     newDefaultMachineClaims(),
 	}
 
 	tflog.Debug(ctx, "orbstack provider configured", map[string]any{"orb_path": cfg.OrbPath})
@@ -100,6 +101,13 @@ func (p *OrbStackProvider) Resources(_ context.Context) []func() resource.Resour
 		NewNetworkConfigResource,
 		NewMachinesGlobalsResource, // exposed as orbstack_machine_config
 		NewK8sResource,
+		NewMachineExportResource,
+		NewMachinePoolResource,
+		NewMachineSettingsResource,
+		NewMachineUserResource,
+		NewMachinePackagesResource,
+		NewMachineServiceResource,
+		NewDefaultMachineResource,
 	}
 }
 
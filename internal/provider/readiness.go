@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Probe defaults, used when interval or timeout is not set.
+const (
+	defaultProbeInterval = 2 * time.Second
+	defaultProbeTimeout  = time.Minute
+)
+
+// durationRegexp matches Go duration strings such as 500ms, 2s or 1m30s.
+var durationRegexp = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`)
+
+// ReadinessModel maps the readiness block of orbstack_machine.
+type ReadinessModel struct {
+	TCPProbes     []ReadinessTCPProbeModel     `tfsdk:"tcp_probe"`
+	CommandProbes []ReadinessCommandProbeModel `tfsdk:"command_probe"`
+	HTTPProbes    []ReadinessHTTPProbeModel    `tfsdk:"http_probe"`
+}
+
+type ReadinessTCPProbeModel struct {
+	Port     types.Int64  `tfsdk:"port"`
+	Host     types.String `tfsdk:"host"`
+	Interval types.String `tfsdk:"interval"`
+	Timeout  types.String `tfsdk:"timeout"`
+}
+
+type ReadinessCommandProbeModel struct {
+	Command  types.String `tfsdk:"command"`
+	User     types.String `tfsdk:"user"`
+	Interval types.String `tfsdk:"interval"`
+	Timeout  types.String `tfsdk:"timeout"`
+}
+
+type ReadinessHTTPProbeModel struct {
+	Port           types.Int64  `tfsdk:"port"`
+	Path           types.String `tfsdk:"path"`
+	Scheme         types.String `tfsdk:"scheme"`
+	Host           types.String `tfsdk:"host"`
+	ExpectedStatus types.Int64  `tfsdk:"expected_status"`
+	Interval       types.String `tfsdk:"interval"`
+	Timeout        types.String `tfsdk:"timeout"`
+}
+
+// withProbeTiming adds the interval and timeout attributes shared by all probes.
+func withProbeTiming(attrs map[string]schema.Attribute) map[string]schema.Attribute {
+	durationValidators := []validator.String{
+		stringvalidator.RegexMatches(durationRegexp, "must be a duration such as 2s or 1m30s"),
+	}
+	attrs["interval"] = schema.StringAttribute{
+		Optional:    true,
+		Description: "Time between attempts (e.g., 2s). Default 2s.",
+		Validators:  durationValidators,
+	}
+	attrs["timeout"] = schema.StringAttribute{
+		Optional:    true,
+		Description: "Time after which the probe fails (e.g., 5m). Default 1m.",
+		Validators:  durationValidators,
+	}
+	return attrs
+}
+
+// readinessProbe is a single probe ready to be polled.
+type readinessProbe struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	check    func(ctx context.Context) error
+	// close releases what the probe holds once polling ends; may be nil
+	close func()
+}
+
+// readinessProbes builds the probes of a readiness block for m, running
+// command probes in the machine currently named machine. Probes without an
+// explicit host target the machine's IP address.
+func readinessProbes(cfg *ClientConfig, machine string, m *MachineModel) []readinessProbe {
+	rd := m.Readiness
+	if rd == nil {
+		return nil
+	}
+	ip := m.IPAddress.ValueString()
+
+	var probes []readinessProbe
+	for i, p := range rd.TCPProbes {
+		addr := net.JoinHostPort(firstNonEmpty(p.Host.ValueString(), ip), strconv.FormatInt(p.Port.ValueInt64(), 10))
+		probes = append(probes, readinessProbe{
+			name:     fmt.Sprintf("tcp_probe[%d] (%s)", i, addr),
+			interval: parseTimeout(p.Interval.ValueString(), defaultProbeInterval),
+			timeout:  parseTimeout(p.Timeout.ValueString(), defaultProbeTimeout),
+			check: func(ctx context.Context) error {
+				var d net.Dialer
+				conn, err := d.DialContext(ctx, "tcp", addr)
+				if err != nil {
+					return err
+				}
+				return conn.Close()
+			},
+		})
+	}
+	for i, p := range rd.CommandProbes {
+		command := p.Command.ValueString()
+		user := firstNonEmpty(p.User.ValueString(), m.Username.ValueString())
+		probes = append(probes, readinessProbe{
+			name:     fmt.Sprintf("command_probe[%d] (%s)", i, command),
+			interval: parseTimeout(p.Interval.ValueString(), defaultProbeInterval),
+			timeout:  parseTimeout(p.Timeout.ValueString(), defaultProbeTimeout),
+			check: func(ctx context.Context) error {
+				_, stderr, err := runInMachine(ctx, cfg, machine, user, command)
+				if err != nil {
+					if msg := strings.TrimSpace(stderr); msg != "" {
+						return fmt.Errorf("%w: %s", err, msg)
+					}
+					return err
+				}
+				return nil
+			},
+		})
+	}
+	for i, p := range rd.HTTPProbes {
+		scheme := firstNonEmpty(p.Scheme.ValueString(), "http")
+		path := firstNonEmpty(p.Path.ValueString(), "/")
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(firstNonEmpty(p.Host.ValueString(), ip), strconv.FormatInt(p.Port.ValueInt64(), 10)), path)
+		want := 200
+		if !p.ExpectedStatus.IsNull() && !p.ExpectedStatus.IsUnknown() {
+			want = int(p.ExpectedStatus.ValueInt64())
+		}
+		// One client per probe; every poll opens a fresh connection
+		client := &http.Client{Transport: &http.Transport{
+			// Machines usually serve self-signed certificates
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		}}
+		probes = append(probes, readinessProbe{
+			name:     fmt.Sprintf("http_probe[%d] (%s)", i, url),
+			interval: parseTimeout(p.Interval.ValueString(), defaultProbeInterval),
+			timeout:  parseTimeout(p.Timeout.ValueString(), defaultProbeTimeout),
+			check: func(ctx context.Context) error {
+				return checkHTTP(ctx, client, url, want)
+			},
+			close: client.CloseIdleConnections,
+		})
+	}
+	return probes
+}
+
+// checkHTTP performs a GET request with client and compares the response status.
+func checkHTTP(ctx context.Context, client *http.Client, url string, want int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		return fmt.Errorf("got HTTP status %d, want %d", resp.StatusCode, want)
+	}
+	return nil
+}
+
+// waitReadiness polls every probe in order until it passes or its timeout
+// elapses. The error names the first probe that did not pass.
+func waitReadiness(ctx context.Context, probes []readinessProbe) error {
+	defer func() {
+		for _, p := range probes {
+			if p.close != nil {
+				p.close()
+			}
+		}
+	}()
+	for _, p := range probes {
+		if err := waitProbe(ctx, p); err != nil {
+			return fmt.Errorf("readiness.%s did not pass within %s: %w", p.name, p.timeout, err)
+		}
+	}
+	return nil
+}
+
+func waitProbe(ctx context.Context, p readinessProbe) error {
+	deadline := time.Now().Add(p.timeout)
+	for {
+		// A single attempt never outlives the probe timeout
+		attemptCtx, cancel := context.WithDeadline(ctx, deadline)
+		err := p.check(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Until(deadline) <= 0 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(p.interval, time.Until(deadline))):
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &DefaultMachineResource{}
+var _ resource.ResourceWithConfigure = &DefaultMachineResource{}
+var _ resource.ResourceWithImportState = &DefaultMachineResource{}
+var _ resource.ResourceWithModifyPlan = &DefaultMachineResource{}
+
+func NewDefaultMachineResource() resource.Resource { return &DefaultMachineResource{} }
+
+// DefaultMachineResource owns OrbStack's default machine setting (`orb default`).
+type DefaultMachineResource struct {
+	client *ClientConfig
+}
+
+type DefaultMachineModel struct {
+	ID      types.String `tfsdk:"id"`
+	Machine types.String `tfsdk:"machine"`
+}
+
+func (r *DefaultMachineResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_default_machine"
+}
+
+func (r *DefaultMachineResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Set OrbStack's default machine, used by `orb` commands that do not name a machine.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Constant identifier (default).",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the machine to make the default.",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+			},
+		},
+	}
+}
+
+func (r *DefaultMachineResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *DefaultMachineResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to plan on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var machine types.String
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("machine"), &machine)...)
+	if resp.Diagnostics.HasError() || machine.IsUnknown() {
+		return
+	}
+	// Instances cannot be told apart at plan time; keying by the machine still
+	// lets instances that name different machines conflict
+	if err := r.client.claimDefaultMachine("orbstack_default_machine machine="+machine.ValueString(), "orbstack_default_machine", machine.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("machine"), "more than one default machine", err.Error())
+	}
+}
+
+func (r *DefaultMachineResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data DefaultMachineModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	if _, stderr, err := runOrb(ctx, r.client.OrbPath, "default", data.Machine.ValueString()); err != nil {
+		resp.Diagnostics.AddError("failed to set default machine", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		return
+	}
+
+	data.ID = types.StringValue("default")
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *DefaultMachineResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data DefaultMachineModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	current, err := currentDefaultMachine(ctx, r.client)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to read default machine", err.Error())
+		return
+	}
+	if current == "" {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	data.ID = types.StringValue("default")
+	data.Machine = types.StringValue(current)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *DefaultMachineResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data DefaultMachineModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	if _, stderr, err := runOrb(ctx, r.client.OrbPath, "default", data.Machine.ValueString()); err != nil {
+		resp.Diagnostics.AddError("failed to set default machine", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *DefaultMachineResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data DefaultMachineModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	// Leave a default chosen outside Terraform in place
+	current, err := currentDefaultMachine(ctx, r.client)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to read default machine", err.Error())
+		return
+	}
+	if current != data.Machine.ValueString() {
+		return
+	}
+
+	if _, stderr, err := runOrb(ctx, r.client.OrbPath, "default", "none"); err != nil {
+		resp.Diagnostics.AddError("failed to unset default machine", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+	}
+}
+
+func (r *DefaultMachineResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// Any import ID works; the current default machine is read on refresh
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), "default")...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("machine"), req.ID)...)
+}
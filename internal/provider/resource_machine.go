@@ -2,28 +2,46 @@ package provider
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+// Machine names follow hostname rules, as OrbStack uses them as hostnames.
+var (
+	machineNameRegexp       = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	machineNamePrefixRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	// image_source_sha256 is a lowercase hex SHA-256 checksum
+	sha256Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// machineNameSuffixLen is the length of the random suffix appended to name_prefix.
+const machineNameSuffixLen = 8
+
 var _ resource.Resource = &MachineResource{}
 var _ resource.ResourceWithImportState = &MachineResource{}
 var _ resource.ResourceWithConfigure = &MachineResource{}
+var _ resource.ResourceWithValidateConfig = &MachineResource{}
+var _ resource.ResourceWithModifyPlan = &MachineResource{}
 
 func NewMachineResource() resource.Resource { return &MachineResource{} }
 
@@ -34,26 +52,74 @@ type MachineResource struct {
 type MachineModel struct {
 	ID            types.String `tfsdk:"id"`
 	Name          types.String `tfsdk:"name"`
+	NamePrefix    types.String `tfsdk:"name_prefix"`
 	Image         types.String `tfsdk:"image"`
 	CloudInit     types.String `tfsdk:"cloud_init"`
 	CloudInitFile types.String `tfsdk:"cloud_init_file"`
 	ValidateImage types.Bool   `tfsdk:"validate_image"`
+	ResolvedImage types.String `tfsdk:"resolved_image"`
+	PinImage      types.Bool   `tfsdk:"pin_image"`
+
+	// Structured cloud-init, rendered to #cloud-config
+	CloudConfig *CloudConfigModel `tfsdk:"cloud_config"`
+
+	// Probes that must pass before creation completes
+	Readiness *ReadinessModel `tfsdk:"readiness"`
+
+	// Source machine for orb clone
+	CloneFrom types.String `tfsdk:"clone_from"`
+
+	// Archive for orb import
+	SourceArchive types.String `tfsdk:"source_archive"`
+
+	// Root filesystem tarball or export archive for orb import, verified by checksum
+	ImageSource       types.String `tfsdk:"image_source"`
+	ImageSourceSHA256 types.String `tfsdk:"image_source_sha256"`
 
 	// User configuration
-	Username types.String `tfsdk:"username"`
+	Username          types.String `tfsdk:"username"`
+	Password          types.String `tfsdk:"password"`
+	PasswordVersion   types.String `tfsdk:"password_version"`
+	SSHAuthorizedKeys types.List   `tfsdk:"ssh_authorized_keys"`
+	AuthorizedKeys    types.List   `tfsdk:"authorized_keys"`
 
 	// Machine configuration
-	PowerState types.String `tfsdk:"power_state"`
-	Arch       types.String `tfsdk:"arch"`
+	PowerState      types.String `tfsdk:"power_state"`
+	RestartTriggers types.Map    `tfsdk:"restart_triggers"`
+	Arch            types.String `tfsdk:"arch"`
+	Emulated        types.Bool   `tfsdk:"emulated"`
 
 	// Default machine setting
 	DefaultMachine types.Bool `tfsdk:"default_machine"`
 
-	IPAddress types.String `tfsdk:"ip_address"`
-	Status    types.String `tfsdk:"status"`
-	SSHHost   types.String `tfsdk:"ssh_host"`
-	SSHPort   types.Int64  `tfsdk:"ssh_port"`
-	CreatedAt types.String `tfsdk:"created_at"`
+	// Ownership marker
+	Adopt          types.Bool   `tfsdk:"adopt"`
+	OwnershipToken types.String `tfsdk:"ownership_token"`
+
+	// Deletion behaviour
+	DeletionProtection types.Bool `tfsdk:"deletion_protection"`
+	GracefulShutdown   types.Bool `tfsdk:"graceful_shutdown"`
+	ForceDelete        types.Bool `tfsdk:"force_delete"`
+
+	// replace or blue_green
+	UpdateStrategy types.String `tfsdk:"update_strategy"`
+
+	// Archive written before the machine is deleted
+	BackupOnDestroy *BackupOnDestroyModel `tfsdk:"backup_on_destroy"`
+
+	IPAddress   types.String `tfsdk:"ip_address"`
+	IPv6Address types.String `tfsdk:"ipv6_address"`
+	Addresses   types.List   `tfsdk:"addresses"`
+	FQDN        types.String `tfsdk:"fqdn"`
+	Status      types.String `tfsdk:"status"`
+	CreatedAt   types.String `tfsdk:"created_at"`
+
+	// Connection details for OrbStack's SSH proxy
+	SSHUser           types.String `tfsdk:"ssh_user"`
+	SSHHost           types.String `tfsdk:"ssh_host"`
+	SSHPort           types.Int64  `tfsdk:"ssh_port"`
+	SSHPrivateKeyPath types.String `tfsdk:"ssh_private_key_path"`
+	SSHCommand        types.String `tfsdk:"ssh_command"`
 }
 
 func (r *MachineResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -69,53 +135,184 @@ func (r *MachineResource) Schema(_ context.Context, _ resource.SchemaRequest, re
 				Description: "Internal identifier (defaults to name).",
 			},
 			"name": schema.StringAttribute{
-				Required:    true,
-				Description: "Machine name (unique).",
-				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				Optional:    true,
+				Computed:    true,
+				Description: "Machine name (unique). Lowercase letters, digits and hyphens, at most 63 characters. Conflicts with name_prefix.",
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 63),
+					stringvalidator.RegexMatches(machineNameRegexp, "must contain only lowercase letters, digits and hyphens, and must not start or end with a hyphen"),
+					stringvalidator.ExactlyOneOf(path.MatchRoot("name_prefix")),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name_prefix": schema.StringAttribute{
+				Optional:    true,
+				Description: "Creates a unique name beginning with this prefix. Conflicts with name.",
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 63-machineNameSuffixLen),
+					stringvalidator.RegexMatches(machineNamePrefixRegexp, "must contain only lowercase letters, digits and hyphens, and must not start with a hyphen"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"image": schema.StringAttribute{
 				Optional:    true,
 				Description: "Base image/distribution (e.g., ubuntu, debian, alpine). Use OS:VERSION format for specific versions (e.g., ubuntu:noble, debian:bookworm). Default ubuntu.",
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					replaceUnlessBlueGreen(),
 				},
 			},
 			"cloud_init": schema.StringAttribute{
 				Optional:    true,
 				Description: "cloud-init user data passed during creation (best-effort).",
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					replaceUnlessBlueGreen(),
 				},
 			},
 			"cloud_init_file": schema.StringAttribute{
 				Optional:    true,
-				Description: "Path to a cloud-init user data file. Overrides cloud_init if both set.",
+				Description: "Path to a cloud-init user data file. Conflicts with cloud_init.",
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					replaceUnlessBlueGreen(),
+				},
+			},
+			"clone_from": schema.StringAttribute{
+				Optional:    true,
+				Description: "Name of an existing machine to clone (orb clone) instead of creating from an image.",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+					stringvalidator.ConflictsWith(
+						path.MatchRoot("image"),
+						path.MatchRoot("arch"),
+						path.MatchRoot("username"),
+						path.MatchRoot("cloud_init"),
+						path.MatchRoot("cloud_init_file"),
+						path.MatchRoot("cloud_config"),
+						path.MatchRoot("validate_image"),
+					),
+				},
+				PlanModifiers: []planmodifier.String{
+					replaceUnlessBlueGreen(),
+				},
+			},
+			"source_archive": schema.StringAttribute{
+				Optional:           true,
+				Description:        "Deprecated alias of image_source, kept for archives created by orb export (or orbstack_machine_export).",
+				DeprecationMessage: "Use image_source instead; it imports the same archives and also replaces the machine when the file changes.",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+					importSourceConflicts,
+				},
+				PlanModifiers: []planmodifier.String{
+					replaceUnlessBlueGreen(),
+				},
+			},
+			"image_source": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to a root filesystem tarball or machine export archive. The machine is created from it with orb import, and replaced when the file's checksum changes.",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+					importSourceConflicts,
+				},
+				PlanModifiers: []planmodifier.String{
+					replaceUnlessBlueGreen(),
+				},
+			},
+			"image_source_sha256": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "SHA-256 checksum of image_source (or source_archive). When set, the file must match it; otherwise the checksum of the file is recorded.",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(sha256Regexp, "must be a lowercase hex SHA-256 checksum"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
 			"validate_image": schema.BoolAttribute{
 				Optional:    true,
 				Description: "Validate image exists before create; fail fast if unknown.",
 			},
+			"resolved_image": schema.StringAttribute{
+				Computed:    true,
+				Description: "Concrete image the machine runs, as distro:version (e.g., ubuntu:noble).",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"pin_image": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Replace the machine when an image alias without version (e.g., ubuntu) now resolves to a different release than resolved_image. Default false.",
+			},
+			"password": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				WriteOnly:   true,
+				Description: "Password for the machine user, set with chpasswd after creation. Write-only: never stored in state. Change password_version to set it again. Requires Terraform 1.11 or later.",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+					stringvalidator.RegexMatches(regexp.MustCompile(`^[^\r\n]*$`), "must not contain line breaks"),
+				},
+			},
+			"password_version": schema.StringAttribute{
+				Optional:    true,
+				Description: "Arbitrary value; changing it sets password again.",
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("password")),
+				},
+			},
 			"username": schema.StringAttribute{
 				Optional:    true,
-				Description: "Username for the default user (defaults to macOS username).",
+				Computed:    true,
+				Description: "Username for the default user. Defaults to the provider's default_user, then to the macOS username.",
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					stringplanmodifier.UseStateForUnknown(),
+					// Changes of the provider default_user are planned in ModifyPlan
+					stringplanmodifier.RequiresReplaceIf(
+						func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+							resp.RequiresReplace = !req.ConfigValue.IsNull() && !isBlueGreen(ctx, req.Plan)
+						},
+						"Changing username requires replacing the machine.",
+						"Changing username requires replacing the machine.",
+					),
 				},
 			},
+			"ssh_authorized_keys": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Public keys to install in the machine user's ~/.ssh/authorized_keys, in addition to the provider's default_ssh_key_path.",
+			},
+			"authorized_keys": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Public keys managed by Terraform that are present in the machine user's authorized_keys.",
+			},
 			"power_state": schema.StringAttribute{
 				Optional:    true,
 				Description: "Desired power state: running or stopped.",
 				Validators:  []validator.String{stringvalidator.OneOf("running", "stopped")},
 			},
+			"restart_triggers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Arbitrary values that restart the machine in place when changed.",
+			},
 			"arch": schema.StringAttribute{
 				Optional:    true,
 				Description: "Architecture passed to orb (-a): amd64 or arm64.",
 				Validators:  []validator.String{stringvalidator.OneOf("amd64", "arm64")},
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					replaceUnlessBlueGreen(),
+				},
+			},
+			"emulated": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the machine's architecture differs from the host's, so it runs emulated (Rosetta or QEMU).",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
 				},
 			},
 			"default_machine": schema.BoolAttribute{
@@ -126,27 +323,278 @@ func (r *MachineResource) Schema(_ context.Context, _ resource.SchemaRequest, re
 					boolplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"adopt": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Take over an existing machine with this name instead of failing. Creation arguments are ignored for an adopted machine. Default false.",
+			},
+			"ownership_token": schema.StringAttribute{
+				Computed:    true,
+				Description: "Token of the ownership marker written when this resource created or adopted the machine.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"deletion_protection": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Refuse to delete the machine while true. Must be set to false and applied before destroy.",
+			},
+			"graceful_shutdown": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Stop the machine and wait up to the provider's delete_timeout for a clean shutdown before deleting it.",
+			},
+			"update_strategy": schema.StringAttribute{
+				Optional:    true,
+				Description: "How changes that need a new machine are applied: replace (delete, then create) or blue_green (create under a temporary name, wait for readiness, swap names with orb rename, then delete the old machine). Default replace.",
+				Validators:  []validator.String{stringvalidator.OneOf("replace", "blue_green")},
+			},
+			"force_delete": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Delete the machine even when graceful_shutdown fails or times out.",
+			},
 			"ip_address": schema.StringAttribute{
 				Computed:    true,
 				Description: "Machine IP address.",
 			},
+			"ipv6_address": schema.StringAttribute{
+				Computed:    true,
+				Description: "Machine IPv6 address.",
+			},
+			"addresses": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "All machine IP addresses (IPv4 first).",
+			},
+			"fqdn": schema.StringAttribute{
+				Computed:    true,
+				Description: "DNS name of the machine (name.orb.local).",
+			},
 			"status": schema.StringAttribute{
 				Computed:    true,
 				Description: "Current status reported by orb info.",
 			},
+			"ssh_user": schema.StringAttribute{
+				Computed:    true,
+				Description: "SSH login for OrbStack's SSH proxy (machine or user@machine).",
+			},
 			"ssh_host": schema.StringAttribute{
 				Computed:    true,
-				Description: "SSH host (usually same as ip_address).",
+				Description: "SSH host of OrbStack's SSH proxy.",
 			},
 			"ssh_port": schema.Int64Attribute{
 				Computed:    true,
-				Description: "SSH port.",
+				Description: "SSH port of OrbStack's SSH proxy.",
+			},
+			"ssh_private_key_path": schema.StringAttribute{
+				Computed:    true,
+				Description: "Private key accepted by OrbStack's SSH proxy.",
+			},
+			"ssh_command": schema.StringAttribute{
+				Computed:    true,
+				Description: "Ready-to-use ssh command line for the machine.",
 			},
 			"created_at": schema.StringAttribute{
 				Computed:    true,
 				Description: "Creation time as reported by orb info.",
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"readiness": schema.SingleNestedBlock{
+				Description: "Probes that must all pass before the machine is considered created. Probes run in order: tcp_probe, command_probe, http_probe.",
+				Blocks: map[string]schema.Block{
+					"tcp_probe": schema.ListNestedBlock{
+						Description: "Wait until a TCP port accepts connections.",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: withProbeTiming(map[string]schema.Attribute{
+								"port": schema.Int64Attribute{
+									Required:    true,
+									Description: "TCP port (e.g., 22).",
+									Validators:  []validator.Int64{int64validator.Between(1, 65535)},
+								},
+								"host": schema.StringAttribute{
+									Optional:    true,
+									Description: "Host to connect to. Defaults to the machine's IP address.",
+								},
+							}),
+						},
+					},
+					"command_probe": schema.ListNestedBlock{
+						Description: "Wait until a command run inside the machine exits with status 0.",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: withProbeTiming(map[string]schema.Attribute{
+								"command": schema.StringAttribute{
+									Required:    true,
+									Description: "Shell command (e.g., systemctl is-active docker).",
+								},
+								"user": schema.StringAttribute{
+									Optional:    true,
+									Description: "User to run the command as. Defaults to the machine's username.",
+								},
+							}),
+						},
+					},
+					"http_probe": schema.ListNestedBlock{
+						Description: "Wait until an HTTP GET request returns the expected status.",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: withProbeTiming(map[string]schema.Attribute{
+								"port": schema.Int64Attribute{
+									Required:    true,
+									Description: "TCP port (e.g., 8080).",
+									Validators:  []validator.Int64{int64validator.Between(1, 65535)},
+								},
+								"path": schema.StringAttribute{
+									Optional:    true,
+									Description: "Request path. Default /.",
+								},
+								"scheme": schema.StringAttribute{
+									Optional:    true,
+									Description: "http or https. Default http. Certificates are not verified.",
+									Validators:  []validator.String{stringvalidator.OneOf("http", "https")},
+								},
+								"host": schema.StringAttribute{
+									Optional:    true,
+									Description: "Host to connect to. Defaults to the machine's IP address.",
+								},
+								"expected_status": schema.Int64Attribute{
+									Optional:    true,
+									Description: "Expected HTTP status code. Default 200.",
+									Validators:  []validator.Int64{int64validator.Between(100, 599)},
+								},
+							}),
+						},
+					},
+				},
+			},
+			"backup_on_destroy": schema.SingleNestedBlock{
+				Description: "Export the machine to a timestamped archive before it is deleted, including deletion for a replacement.",
+				Attributes: map[string]schema.Attribute{
+					"directory": schema.StringAttribute{
+						Required:    true,
+						Description: "Host directory for the archives (e.g., ./backups).",
+						Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+					},
+					"retention": schema.Int64Attribute{
+						Optional:    true,
+						Description: "Number of archives of this machine to keep; older ones are removed. Default 5.",
+						Validators:  []validator.Int64{int64validator.AtLeast(1)},
+					},
+				},
+			},
+			"cloud_config": schema.SingleNestedBlock{
+				Description: "Structured cloud-init configuration rendered to a #cloud-config document. Merged with cloud_init or cloud_init_file when both are set.",
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.RequiresReplaceIf(replaceObjectUnlessBlueGreen, blueGreenReplaceDescription, blueGreenReplaceDescription),
+				},
+				Attributes: map[string]schema.Attribute{
+					"packages": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "Packages to install on first boot.",
+					},
+					"package_update": schema.BoolAttribute{
+						Optional:    true,
+						Description: "Update the package index on first boot.",
+					},
+					"package_upgrade": schema.BoolAttribute{
+						Optional:    true,
+						Description: "Upgrade installed packages on first boot.",
+					},
+					"runcmd": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "Commands to run at the end of first boot.",
+					},
+					"timezone": schema.StringAttribute{
+						Optional:    true,
+						Description: "Timezone (e.g., Europe/Amsterdam).",
+					},
+				},
+				Blocks: map[string]schema.Block{
+					"write_files": schema.ListNestedBlock{
+						Description: "Files to write on first boot.",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: map[string]schema.Attribute{
+								"path": schema.StringAttribute{
+									Required:    true,
+									Description: "Absolute path of the file.",
+								},
+								"content": schema.StringAttribute{
+									Required:    true,
+									Description: "File content.",
+								},
+								"permissions": schema.StringAttribute{
+									Optional:    true,
+									Description: "Octal file mode (e.g., 0644).",
+								},
+								"owner": schema.StringAttribute{
+									Optional:    true,
+									Description: "Owner as user:group.",
+								},
+								"append": schema.BoolAttribute{
+									Optional:    true,
+									Description: "Append to the file instead of replacing it.",
+								},
+							},
+						},
+					},
+					"users": schema.ListNestedBlock{
+						Description: "Users to create on first boot.",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: map[string]schema.Attribute{
+								"name": schema.StringAttribute{
+									Required:    true,
+									Description: "User name.",
+								},
+								"groups": schema.ListAttribute{
+									ElementType: types.StringType,
+									Optional:    true,
+									Description: "Supplementary groups.",
+								},
+								"shell": schema.StringAttribute{
+									Optional:    true,
+									Description: "Login shell.",
+								},
+								"sudo": schema.StringAttribute{
+									Optional:    true,
+									Description: "Sudo rule (e.g., ALL=(ALL) NOPASSWD:ALL).",
+								},
+								"lock_passwd": schema.BoolAttribute{
+									Optional:    true,
+									Description: "Disable password login.",
+								},
+								"ssh_authorized_keys": schema.ListAttribute{
+									ElementType: types.StringType,
+									Optional:    true,
+									Description: "Public keys added to the user's authorized_keys.",
+								},
+							},
+						},
+					},
+					"apt_sources": schema.ListNestedBlock{
+						Description: "Additional apt sources (Debian/Ubuntu only).",
+						NestedObject: schema.NestedBlockObject{
+							Attributes: map[string]schema.Attribute{
+								"name": schema.StringAttribute{
+									Required:    true,
+									Description: "Source name, used as the sources.list.d file name.",
+								},
+								"source": schema.StringAttribute{
+									Required:    true,
+									Description: "Source line (e.g., deb http://example.com/apt stable main).",
+								},
+								"key": schema.StringAttribute{
+									Optional:    true,
+									Description: "ASCII-armored signing key.",
+								},
+								"keyid": schema.StringAttribute{
+									Optional:    true,
+									Description: "Signing key ID fetched from the keyserver.",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -158,112 +606,426 @@ func (r *MachineResource) Configure(_ context.Context, req resource.ConfigureReq
 	r.client = cfg
 }
 
-func (r *MachineResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan MachineModel
-	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+func (r *MachineResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cloudInit, cloudInitFile, imageSource, sourceArchive, imageSourceSHA256 types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("cloud_init"), &cloudInit)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("cloud_init_file"), &cloudInitFile)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("image_source"), &imageSource)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("source_archive"), &sourceArchive)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("image_source_sha256"), &imageSourceSHA256)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	cfg := r.client
-	if cfg == nil {
-		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+	if !imageSourceSHA256.IsNull() && imageSource.IsNull() && sourceArchive.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("image_source_sha256"),
+			"missing image_source",
+			"image_source_sha256 requires image_source or source_archive.",
+		)
+	}
+
+	if !cloudInit.IsNull() && !cloudInitFile.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("cloud_init_file"),
+			"conflicting cloud-init sources",
+			"Only one of cloud_init and cloud_init_file can be set.",
+		)
 		return
 	}
 
-	name := plan.Name.ValueString()
-	image := plan.Image.ValueString()
-	if image == "" {
-		image = "ubuntu"
+	// Unknown values are validated again once they are known
+	attr := path.Root("cloud_init")
+	var content string
+	switch {
+	case !cloudInit.IsNull() && !cloudInit.IsUnknown():
+		content = cloudInit.ValueString()
+	case !cloudInitFile.IsNull() && !cloudInitFile.IsUnknown():
+		attr = path.Root("cloud_init_file")
+		b, err := os.ReadFile(cloudInitFile.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(attr, "cloud_init_file not readable", err.Error())
+			return
+		}
+		content = string(b)
+	default:
+		return
+	}
+	if strings.TrimSpace(content) == "" {
+		return
 	}
 
-	args := []string{"create"}
+	warnings, err := validateCloudInit(content)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(attr, "invalid cloud-init user data", err.Error())
+		return
+	}
+	for _, w := range warnings {
+		resp.Diagnostics.AddAttributeWarning(attr, "unknown cloud-init module", w)
+	}
+}
+
+func (r *MachineResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to plan on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	r.planUsername(ctx, req, resp)
+	r.planAuthorizedKeys(ctx, req, resp)
+	r.planPinnedImage(ctx, req, resp)
+	r.planImageSource(ctx, req, resp)
+	r.planArch(ctx, req, resp)
+	r.planDefaultMachine(ctx, req, resp)
+	r.planAdoption(ctx, req, resp)
+	r.planBlueGreen(ctx, req, resp)
+}
+
+// planAdoption plans a new ownership marker when adopt = true and the machine
+// has no marker of this resource, e.g. after terraform import.
+func (r *MachineResource) planAdoption(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() {
+		return
+	}
 
-	// cloud-init: file takes precedence over inline
-	if f := strings.TrimSpace(plan.CloudInitFile.ValueString()); f != "" {
-		// ensure the file exists and pass absolute path
-		if _, err := os.Stat(f); err != nil {
-			resp.Diagnostics.AddError("cloud_init_file not found", err.Error())
+	var adopt types.Bool
+	var name, token types.String
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("adopt"), &adopt)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("name"), &name)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("ownership_token"), &token)...)
+	if resp.Diagnostics.HasError() || !adopt.ValueBool() {
+		return
+	}
+
+	owner, ok, err := lookupOwner(name.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddWarning("cannot read ownership registry", err.Error())
+		return
+	}
+	if ok && owner.Token == token.ValueString() {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ownership_token"), types.StringUnknown())...)
+}
+
+// planDefaultMachine fails the plan when another resource sets a different
+// machine as the default. Claims are keyed per resource instance: by the state
+// ID, which survives renames, or by the planned name until the machine exists.
+func (r *MachineResource) planDefaultMachine(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var isDefault types.Bool
+	var name, id types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("default_machine"), &isDefault)...)
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("name"), &name)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("id"), &id)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	key := "orbstack_machine id=" + id.ValueString()
+	if id.ValueString() == "" {
+		if name.IsUnknown() {
+			// A generated or computed name cannot identify the instance
+			if isDefault.ValueBool() {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("default_machine"),
+					"default machine name not known",
+					"default_machine = true requires a name that is known at plan time, so conflicts with other default machines can be detected. Set name instead of name_prefix, or use orbstack_default_machine.",
+				)
+			}
 			return
 		}
-		abs, err := filepath.Abs(f)
-		if err != nil {
-			resp.Diagnostics.AddError("failed to resolve cloud_init_file path", err.Error())
+		key = "orbstack_machine name=" + name.ValueString()
+	}
+	if !isDefault.ValueBool() {
+		r.client.releaseDefaultMachine(key)
+		return
+	}
+	// A renamed machine is checked again once its new name is known
+	if name.IsUnknown() {
+		return
+	}
+
+	machine := name.ValueString()
+	if err := r.client.claimDefaultMachine(key, fmt.Sprintf("orbstack_machine %q", machine), machine); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("default_machine"), "more than one default machine", err.Error())
+	}
+}
+
+// planArch checks a new machine's arch against the host: arm64 machines cannot
+// run on Intel Macs, and amd64 machines on Apple Silicon need Rosetta to avoid
+// slow QEMU emulation.
+func (r *MachineResource) planArch(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if !req.State.Raw.IsNull() {
+		return
+	}
+
+	var arch types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("arch"), &arch)...)
+	if resp.Diagnostics.HasError() || arch.IsNull() || arch.IsUnknown() {
+		return
+	}
+
+	want, host := normalizeArch(arch.ValueString()), hostArch()
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("emulated"), types.BoolValue(want != host))...)
+	if want == host {
+		return
+	}
+
+	switch {
+	case want == "arm64" && host == "amd64":
+		resp.Diagnostics.AddAttributeError(
+			path.Root("arch"),
+			"unsupported architecture",
+			"arm64 machines cannot run on an Intel Mac. Remove arch or set arch = \"amd64\".",
+		)
+	case want == "amd64" && host == "arm64" && r.client != nil:
+		rosetta, diags := readConfig(ctx, r.client, "rosetta")
+		if diags.HasError() {
+			// Older orb versions may not expose the key; orb create decides then
+			tflog.Debug(ctx, "could not read rosetta setting", map[string]any{"error": diags.Errors()[0].Detail()})
 			return
 		}
-		args = append(args, "-c", abs)
-	} else if v := plan.CloudInit.ValueString(); strings.TrimSpace(v) != "" {
-		tmpFile, err := os.CreateTemp("", "orbstack-cloudinit-*.yaml")
-		if err != nil {
-			resp.Diagnostics.AddError("failed to create temp file for cloud-init", err.Error())
-			return
+		if !strings.EqualFold(strings.TrimSpace(rosetta), "true") {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("arch"),
+				"amd64 machine without Rosetta",
+				"Rosetta is disabled in OrbStack, so this amd64 machine will run under much slower QEMU emulation. Enable it with orbstack_config (rosetta = true).",
+			)
 		}
-		defer os.Remove(tmpFile.Name())
-		if _, err := tmpFile.WriteString(v); err != nil {
-			resp.Diagnostics.AddError("failed to write cloud-init to temp file", err.Error())
+	}
+}
+
+// planPinnedImage replaces a machine with pin_image set when its image alias now
+// resolves to a different release than the one recorded in resolved_image.
+func (r *MachineResource) planPinnedImage(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan MachineModel
+	var prior types.String
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("resolved_image"), &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !plan.PinImage.ValueBool() || !plan.CloneFrom.IsNull() || !plan.SourceArchive.IsNull() || !plan.ImageSource.IsNull() {
+		return
+	}
+	if plan.Image.IsUnknown() || prior.IsNull() || prior.IsUnknown() {
+		return
+	}
+	alias := firstNonEmpty(strings.TrimSpace(plan.Image.ValueString()), "ubuntu")
+	if strings.Contains(alias, ":") {
+		// Explicit versions always resolve to themselves
+		return
+	}
+
+	current, err := resolveImageAlias(ctx, r.client, alias)
+	if err != nil {
+		resp.Diagnostics.AddAttributeWarning(path.Root("pin_image"), "cannot resolve image alias", err.Error())
+		return
+	}
+	// Both sides are normalized, so only a real release change replaces the machine
+	if normalizeImage(current) == normalizeImage(prior.ValueString()) {
+		return
+	}
+
+	resp.Diagnostics.AddAttributeWarning(
+		path.Root("pin_image"),
+		"image alias resolves to a new release",
+		fmt.Sprintf("Image %q now resolves to %s; the machine was created with %s and will be rebuilt.", alias, current, prior.ValueString()),
+	)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("resolved_image"), types.StringUnknown())...)
+	if !isBlueGreen(ctx, resp.Plan) {
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("resolved_image"))
+	}
+}
+
+// planUsername falls back to the provider's default_user when username is not
+// configured, and replaces the machine when that default changes.
+func (r *MachineResource) planUsername(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var configured, cloneFrom, sourceArchive, imageSource types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("username"), &configured)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("clone_from"), &cloneFrom)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("source_archive"), &sourceArchive)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("image_source"), &imageSource)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Cloned and imported machines keep the user of their source
+	if !configured.IsNull() || !cloneFrom.IsNull() || !sourceArchive.IsNull() || !imageSource.IsNull() {
+		return
+	}
+	if r.client == nil || r.client.DefaultUser == "" {
+		return
+	}
+	def := types.StringValue(r.client.DefaultUser)
+
+	if !req.State.Raw.IsNull() {
+		var prior types.String
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("username"), &prior)...)
+		// An unresolved prior user is filled in by the next apply instead of replacing
+		if prior.IsNull() || prior.IsUnknown() {
 			return
 		}
-		if err := tmpFile.Close(); err != nil {
-			resp.Diagnostics.AddError("failed to close cloud-init temp file", err.Error())
-			return
+		if !prior.Equal(def) && !isBlueGreen(ctx, resp.Plan) {
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root("username"))
 		}
-		args = append(args, "-c", tmpFile.Name())
 	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("username"), def)...)
+}
 
-	// set_password removed (interactive-only flag not supported by Terraform)
-
-	// Architecture flag
-	if v := strings.TrimSpace(plan.Arch.ValueString()); v != "" {
-		args = append(args, "-a", v)
+// planAuthorizedKeys plans the effective key set (provider default key plus
+// ssh_authorized_keys), so keys removed inside the machine are reinstalled.
+func (r *MachineResource) planAuthorizedKeys(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var configured types.List
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("ssh_authorized_keys"), &configured)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	// Username flag
-	if v := strings.TrimSpace(plan.Username.ValueString()); v != "" {
-		args = append(args, "-u", v)
+	unknown := configured.IsUnknown()
+	for _, e := range configured.Elements() {
+		unknown = unknown || e.IsUnknown()
+	}
+	if unknown {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("authorized_keys"), types.ListUnknown(types.StringType))...)
+		return
 	}
 
-	// Use image directly (may include OS:VERSION format)
-	imageArg := image
+	desired, err := desiredAuthorizedKeys(r.client, listToStrings(ctx, configured, &resp.Diagnostics))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("ssh_authorized_keys"), "invalid SSH public key", err.Error())
+		return
+	}
 
-	// Validate image if requested
-	if plan.ValidateImage.ValueBool() {
-		known, d := listAvailableImages(ctx, cfg)
+	planned := types.ListNull(types.StringType)
+	if len(desired) > 0 {
+		var d diag.Diagnostics
+		planned, d = types.ListValueFrom(ctx, types.StringType, desired)
 		resp.Diagnostics.Append(d...)
-		if resp.Diagnostics.HasError() {
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("authorized_keys"), planned)...)
+}
+
+func (r *MachineResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan MachineModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cfg := r.client
+	if cfg == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	if plan.Name.IsUnknown() || plan.Name.IsNull() {
+		generated, err := uniqueMachineName(plan.NamePrefix.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("failed to generate machine name", err.Error())
 			return
 		}
-		if _, ok := known[strings.ToLower(imageArg)]; !ok {
-			resp.Diagnostics.AddError("unknown image", fmt.Sprintf("image '%s' not found by orb", imageArg))
+		plan.Name = types.StringValue(generated)
+	}
+	name := plan.Name.ValueString()
+
+	// Never take over a machine this configuration did not create
+	adopted := false
+	if machineExists(ctx, cfg, name) {
+		if !plan.Adopt.ValueBool() {
+			resp.Diagnostics.AddError(
+				"machine already exists",
+				fmt.Sprintf("A machine named %q already exists and was not created by this resource. Set adopt = true to manage it.", name),
+			)
 			return
 		}
+		adopted = true
 	}
 
-	args = append(args, imageArg, name)
+	if !adopted {
+		// Stat the import source before it is hashed so a later change is never missed
+		_, source := importSource(&plan)
+		stamp, stampErr := statImageSource(source)
+		resp.Diagnostics.Append(provisionMachine(ctx, cfg, &plan, name)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if source != "" && stampErr == nil {
+			stamp.SHA256 = plan.ImageSourceSHA256.ValueString()
+			resp.Diagnostics.Append(storeImageSourceStamp(ctx, resp.Private, stamp)...)
+		}
+	}
 
-	_, stderr, err := runOrb(ctx, cfg.OrbPath, args...)
-	if err != nil {
-		resp.Diagnostics.AddError("failed to create machine", fmt.Sprintf("orb error: %s", stderr))
+	model, diags := readUntilReady(ctx, cfg, name, cfg.CreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if model == nil {
+		resp.Diagnostics.AddError("machine not found after creation", name)
 		return
 	}
 
-	model, diags := readUntilReady(ctx, cfg, name, cfg.CreateTimeout)
-	resp.Diagnostics.Append(diags...)
-	if resp.Diagnostics.HasError() {
+	// Record this configuration as the owner, replacing the marker of an adopted machine
+	token, diags := claimOwnership(name, model.CreatedAt.ValueString(), adopted)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.OwnershipToken = types.StringValue(token)
+
+	plan.ID = types.StringValue(name)
+	// Record the user orb created when neither username nor default_user was set
+	if plan.Username.IsUnknown() || plan.Username.IsNull() {
+		user, err := machineDefaultUser(ctx, cfg, name)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to determine machine user", err.Error())
+			return
+		}
+		plan.Username = types.StringValue(user)
+	}
+
+	copyMachineInfo(&plan, model)
+	if plan.ResolvedImage.IsNull() {
+		if image, err := machineOSImage(ctx, cfg, name); err == nil {
+			plan.ResolvedImage = types.StringValue(image)
+		}
+	}
+
+	// Write-only: the password is only available in config
+	var password types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("password"), &password)...)
+	if !password.IsNull() && !password.IsUnknown() {
+		if err := setMachinePassword(ctx, cfg, name, plan.Username.ValueString(), password.ValueString()); err != nil {
+			resp.Diagnostics.AddError("failed to set password", err.Error())
+			return
+		}
+	}
+
+	// Install authorized keys while the machine is running
+	keys := listToStrings(ctx, plan.AuthorizedKeys, &resp.Diagnostics)
+	if err := syncAuthorizedKeys(ctx, cfg, name, plan.Username.ValueString(), keys, nil); err != nil {
+		resp.Diagnostics.AddError("failed to install SSH keys", err.Error())
 		return
 	}
 
-	if model == nil {
-		resp.Diagnostics.AddError("machine not found after creation", name)
+	// Wait for the readiness probes; a failing machine is kept in state and tainted
+	if err := waitReadiness(ctx, readinessProbes(cfg, name, &plan)); err != nil {
+		isDefault, _ := r.isDefaultMachine(ctx, cfg, name)
+		plan.DefaultMachine = types.BoolValue(isDefault)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		resp.Diagnostics.AddError("machine did not become ready", err.Error())
 		return
 	}
 
-	plan.ID = types.StringValue(name)
-	plan.IPAddress = model.IPAddress
-	plan.Status = model.Status
-	plan.SSHHost = model.SSHHost
-	plan.SSHPort = model.SSHPort
-	plan.CreatedAt = model.CreatedAt
-
 	// Enforce desired power state after creation
 	desired := strings.TrimSpace(plan.PowerState.ValueString())
 	if desired == "stopped" {
@@ -278,8 +1040,7 @@ func (r *MachineResource) Create(ctx context.Context, req resource.CreateRequest
 		if resp.Diagnostics.HasError() {
 			return
 		}
-		plan.Status = model.Status
-		plan.IPAddress = model.IPAddress
+		copyMachineInfo(&plan, model)
 	}
 
 	// Set as default machine if requested
@@ -302,6 +1063,119 @@ func (r *MachineResource) Create(ctx context.Context, req resource.CreateRequest
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
+// provisionMachine creates machine name from plan with orb clone, orb import or
+// orb create, bounded by the create timeout for imports.
+func provisionMachine(ctx context.Context, cfg *ClientConfig, plan *MachineModel, name string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var args []string
+	action := "create"
+	runCtx := ctx
+	if src := strings.TrimSpace(plan.CloneFrom.ValueString()); src != "" {
+		// Clone an existing machine instead of provisioning from an image
+		action = "clone"
+		args = []string{"clone", src, name}
+	} else if attr, source := importSource(plan); source != "" {
+		// Import a root filesystem or export archive after verifying its checksum
+		abs, sum, err := imageSourceChecksum(source)
+		if err != nil {
+			diags.AddAttributeError(attr, attr.String()+" not readable", err.Error())
+			return diags
+		}
+		if want := plan.ImageSourceSHA256.ValueString(); !plan.ImageSourceSHA256.IsUnknown() && want != "" && want != sum {
+			diags.AddAttributeError(
+				path.Root("image_source_sha256"),
+				attr.String()+" checksum mismatch",
+				fmt.Sprintf("%s has SHA-256 %s, expected %s. The file changed since the plan or is not the expected image.", abs, sum, want),
+			)
+			return diags
+		}
+		plan.ImageSourceSHA256 = types.StringValue(sum)
+		action = "import"
+		args = []string{"import", "-n", name, abs}
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, parseTimeout(cfg.CreateTimeout, 5*time.Minute))
+		defer cancel()
+	} else {
+		createArgs, cleanup, d := machineCreateArgs(ctx, cfg, plan, name)
+		defer cleanup()
+		diags.Append(d...)
+		if diags.HasError() {
+			return diags
+		}
+		args = createArgs
+	}
+
+	_, stderr, err := runOrb(runCtx, cfg.OrbPath, args...)
+	if err != nil {
+		if runCtx.Err() == context.DeadlineExceeded {
+			diags.AddError("failed to "+action+" machine", fmt.Sprintf("orb %s did not finish within create_timeout (%s)", action, cfg.CreateTimeout))
+			return diags
+		}
+		diags.AddError("failed to "+action+" machine", fmt.Sprintf("orb error: %s", stderr))
+		return diags
+	}
+	if plan.ImageSourceSHA256.IsUnknown() {
+		plan.ImageSourceSHA256 = types.StringNull()
+	}
+	return diags
+}
+
+// machineCreateArgs builds the orb create arguments for a machine plan. The
+// returned cleanup removes any temporary cloud-init file once orb has run.
+func machineCreateArgs(ctx context.Context, cfg *ClientConfig, plan *MachineModel, name string) ([]string, func(), diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	image := plan.Image.ValueString()
+	if image == "" {
+		image = "ubuntu"
+	}
+
+	args := []string{"create"}
+
+	// cloud-init: inline or file; cloud_config is merged into either
+	cloudInitPath, cleanup, d := prepareCloudInit(ctx, plan)
+	diags.Append(d...)
+	if diags.HasError() {
+		return nil, cleanup, diags
+	}
+	if cloudInitPath != "" {
+		args = append(args, "-c", cloudInitPath)
+	}
+
+	// orb's password flag is interactive; password is set with chpasswd after creation instead
+
+	// Architecture flag
+	if v := strings.TrimSpace(plan.Arch.ValueString()); v != "" {
+		args = append(args, "-a", v)
+	}
+
+	// Username flag
+	if v := strings.TrimSpace(plan.Username.ValueString()); v != "" {
+		args = append(args, "-u", v)
+	}
+
+	// Use image directly (may include OS:VERSION format)
+	imageArg := image
+
+	// Validate image if requested
+	if plan.ValidateImage.ValueBool() {
+		known, d := listAvailableImages(ctx, cfg)
+		diags.Append(d...)
+		if diags.HasError() {
+			return nil, cleanup, diags
+		}
+		if _, ok := known[strings.ToLower(imageArg)]; !ok {
+			diags.AddError("unknown image", fmt.Sprintf("image '%s' not found by orb", imageArg))
+			return nil, cleanup, diags
+		}
+	}
+
+	args = append(args, imageArg, name)
+
+	return args, cleanup, diags
+}
+
 func (r *MachineResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
 	var state MachineModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -328,11 +1202,42 @@ func (r *MachineResource) Read(ctx context.Context, req resource.ReadRequest, re
 		return
 	}
 
-	state.IPAddress = model.IPAddress
-	state.Status = model.Status
-	state.SSHHost = model.SSHHost
-	state.SSHPort = model.SSHPort
-	state.CreatedAt = model.CreatedAt
+	// Imported machines have no recorded user yet
+	if (state.Username.IsNull() || state.Username.IsUnknown()) && isMachineRunning(model) {
+		user, err := machineDefaultUser(ctx, cfg, name)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to determine machine user", err.Error())
+			return
+		}
+		state.Username = types.StringValue(user)
+	}
+
+	copyMachineInfo(&state, model)
+	state.OwnershipToken, diags = claimLegacyMachine(ctx, req.Private, name, state.CreatedAt.ValueString(), state.OwnershipToken)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Imported machines have no recorded image yet
+	if state.ResolvedImage.IsNull() && isMachineRunning(model) {
+		if image, err := machineOSImage(ctx, cfg, name); err == nil {
+			state.ResolvedImage = types.StringValue(image)
+		}
+	}
+
+	// Detect managed keys removed inside the machine; stopped machines keep their state
+	if !state.AuthorizedKeys.IsNull() && isMachineRunning(model) {
+		keys := listToStrings(ctx, state.AuthorizedKeys, &resp.Diagnostics)
+		present, err := presentAuthorizedKeys(ctx, cfg, name, state.Username.ValueString(), keys)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to read SSH keys", err.Error())
+			return
+		}
+		list, d := types.ListValueFrom(ctx, types.StringType, present)
+		resp.Diagnostics.Append(d...)
+		state.AuthorizedKeys = list
+	}
 
 	// Check if this machine is the current default
 	isDefault, diags := r.isDefaultMachine(ctx, cfg, name)
@@ -364,18 +1269,96 @@ func (r *MachineResource) Update(ctx context.Context, req resource.UpdateRequest
 	oldName := state.Name.ValueString()
 	newName := plan.Name.ValueString()
 
+	// Machines created before ownership markers existed are claimed, not refused
+	if state.OwnershipToken.IsNull() {
+		token, diags := claimLegacyMachine(ctx, req.Private, oldName, state.CreatedAt.ValueString(), state.OwnershipToken)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		state.OwnershipToken = token
+		if !token.IsNull() && plan.OwnershipToken.IsUnknown() {
+			plan.OwnershipToken = token
+		}
+	}
+
+	// adopt = true takes over a machine without a marker of this resource
+	if plan.Adopt.ValueBool() && plan.OwnershipToken.IsUnknown() {
+		token, diags := claimOwnership(oldName, state.CreatedAt.ValueString(), true)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		plan.OwnershipToken = types.StringValue(token)
+		state.OwnershipToken = plan.OwnershipToken
+	}
+
+	// Build a new machine next to the old one and swap it in; it gets its
+	// name, keys and password while being built
+	rebuilt := false
+	if plan.UpdateStrategy.ValueString() == "blue_green" && machineNeedsRebuild(&plan, &state) {
+		resp.Diagnostics.Append(r.blueGreenReplace(ctx, req.Config, &plan, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		rebuilt = true
+	}
+
 	// If the name changed, perform a rename using orb CLI
-	if oldName != "" && newName != "" && oldName != newName {
+	if !rebuilt && oldName != "" && newName != "" && oldName != newName {
 		args := []string{"rename", oldName, newName}
 		_, stderr, err := runOrb(ctx, cfg.OrbPath, args...)
 		if err != nil {
 			resp.Diagnostics.AddError("failed to rename machine", fmt.Sprintf("orb error: %s", stderr))
 			return
 		}
+		if err := renameOwnership(oldName, newName); err != nil {
+			resp.Diagnostics.AddWarning("failed to move ownership marker", err.Error())
+		}
 	}
 
-	// Power state changes
+	// Sync authorized keys before a possible stop
+	if !rebuilt && !plan.AuthorizedKeys.Equal(state.AuthorizedKeys) {
+		want := listToStrings(ctx, plan.AuthorizedKeys, &resp.Diagnostics)
+		had := listToStrings(ctx, state.AuthorizedKeys, &resp.Diagnostics)
+		if err := syncAuthorizedKeys(ctx, cfg, newName, plan.Username.ValueString(), want, stringsDiff(had, want)); err != nil {
+			resp.Diagnostics.AddError("failed to update SSH keys", err.Error())
+			return
+		}
+	}
+
+	// Rotate the password when password_version changes
+	if !rebuilt && !plan.PasswordVersion.Equal(state.PasswordVersion) && !plan.PasswordVersion.IsNull() {
+		var password types.String
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("password"), &password)...)
+		if password.IsNull() || password.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(path.Root("password"), "password not available", "password_version changed, but password is not set in the configuration.")
+			return
+		}
+		if err := setMachinePassword(ctx, cfg, newName, plan.Username.ValueString(), password.ValueString()); err != nil {
+			resp.Diagnostics.AddError("failed to set password", err.Error())
+			return
+		}
+	}
+
+	// Restart in place when restart_triggers change, unless the machine is
+	// stopped or about to be stopped; it picks up the change on its next start
 	desired := strings.TrimSpace(plan.PowerState.ValueString())
+	if !rebuilt && !plan.RestartTriggers.Equal(state.RestartTriggers) && desired != "stopped" {
+		current, diags := readMachine(ctx, cfg, newName)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if current != nil && isMachineRunning(current) {
+			if err := restartMachine(ctx, cfg, newName); err != nil {
+				resp.Diagnostics.AddError("failed to restart machine", err.Error())
+				return
+			}
+		}
+	}
+
+	// Power state changes
 	if desired == "running" {
 		_, _, _ = runOrb(ctx, cfg.OrbPath, "start", newName)
 	} else if desired == "stopped" {
@@ -415,11 +1398,18 @@ func (r *MachineResource) Update(ctx context.Context, req resource.UpdateRequest
 	}
 
 	plan.ID = types.StringValue(newName)
-	plan.IPAddress = model.IPAddress
-	plan.Status = model.Status
-	plan.SSHHost = model.SSHHost
-	plan.SSHPort = model.SSHPort
-	plan.CreatedAt = model.CreatedAt
+	if plan.Username.IsUnknown() {
+		plan.Username = types.StringNull()
+		if isMachineRunning(model) {
+			user, err := machineDefaultUser(ctx, cfg, newName)
+			if err != nil {
+				resp.Diagnostics.AddError("failed to determine machine user", err.Error())
+				return
+			}
+			plan.Username = types.StringValue(user)
+		}
+	}
+	copyMachineInfo(&plan, model)
 
 	// Refresh default_machine to a known value after update
 	isDefaultAfter, diags2 := r.isDefaultMachine(ctx, cfg, newName)
@@ -428,6 +1418,10 @@ func (r *MachineResource) Update(ctx context.Context, req resource.UpdateRequest
 		return
 	}
 	plan.DefaultMachine = types.BoolValue(isDefaultAfter)
+	// An imported machine keeps its missing marker until it is adopted
+	if plan.OwnershipToken.IsUnknown() {
+		plan.OwnershipToken = state.OwnershipToken
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
@@ -447,16 +1441,134 @@ func (r *MachineResource) Delete(ctx context.Context, req resource.DeleteRequest
 
 	name := state.Name.ValueString()
 
+	if state.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddError(
+			"machine is protected from deletion",
+			fmt.Sprintf("Machine %q has deletion_protection enabled. Set deletion_protection = false and apply before destroying it.", name),
+		)
+		return
+	}
+
+	token, diags := claimLegacyMachine(ctx, req.Private, name, state.CreatedAt.ValueString(), state.OwnershipToken)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.OwnershipToken = token
+	if err := checkMachineOwner(ctx, cfg, name, state.OwnershipToken.ValueString()); err != nil {
+		resp.Diagnostics.AddError("machine is not owned by this resource", err.Error())
+		return
+	}
+
+	if state.GracefulShutdown.ValueBool() {
+		timeout := parseTimeout(cfg.DeleteTimeout, 5*time.Minute)
+		if err := stopMachineGracefully(ctx, cfg, name, timeout); err != nil {
+			if !state.ForceDelete.ValueBool() {
+				resp.Diagnostics.AddError("machine did not shut down cleanly", err.Error()+"\nSet force_delete = true to delete it anyway.")
+				return
+			}
+			tflog.Warn(ctx, "graceful shutdown failed, deleting anyway", map[string]any{"machine": name, "error": err.Error()})
+		}
+	}
+
+	// Back up after a graceful shutdown, so the archive is consistent
+	if state.BackupOnDestroy != nil {
+		dest, err := backupMachine(ctx, cfg, name, state.BackupOnDestroy)
+		if dest == "" {
+			resp.Diagnostics.AddError("failed to back up machine", err.Error()+"\nThe machine was not deleted.")
+			return
+		}
+		if err != nil {
+			resp.Diagnostics.AddWarning("failed to prune backups", err.Error())
+		}
+		tflog.Info(ctx, "machine backed up before deletion", map[string]any{"machine": name, "archive": dest})
+	}
+
 	args := []string{"delete", name}
 	_, stderr, err := runOrb(ctx, cfg.OrbPath, args...)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to delete machine", fmt.Sprintf("orb error: %s", stderr))
 		return
 	}
+
+	if err := releaseMachine(name, state.OwnershipToken.ValueString()); err != nil {
+		resp.Diagnostics.AddWarning("failed to remove ownership marker", err.Error())
+	}
+}
+
+// claimOwnership records this configuration as the owner of a machine and
+// returns the new token. Adopting a machine owned by another configuration
+// produces a warning.
+func claimOwnership(name, createdAt string, adopted bool) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if prior, ok, _ := lookupOwner(name); ok && adopted {
+		diags.AddWarning("adopted machine owned by another configuration", fmt.Sprintf("Machine %q was owned by %s and is now owned by this resource.", name, prior))
+	}
+	owner, err := currentOwner(createdAt)
+	if err == nil {
+		err = claimMachine(name, owner)
+	}
+	if err != nil {
+		diags.AddError("failed to record machine ownership", err.Error())
+		return "", diags
+	}
+	return owner.Token, diags
+}
+
+// importedPrivateKey marks machines brought in with terraform import. Their
+// missing ownership marker does not come from an older provider version.
+const importedPrivateKey = "imported"
+
+// privateStateGetter is implemented by the private state of resource requests.
+type privateStateGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// claimLegacyMachine records this configuration as the owner of a machine in
+// state that a provider version without ownership markers created. Imported
+// machines and machines with a marker are left alone. It returns the token to
+// keep in state.
+func claimLegacyMachine(ctx context.Context, private privateStateGetter, name, createdAt string, token types.String) (types.String, diag.Diagnostics) {
+	if !token.IsNull() && !token.IsUnknown() {
+		return token, nil
+	}
+	imported, diags := private.GetKey(ctx, importedPrivateKey)
+	if diags.HasError() || imported != nil {
+		return token, diags
+	}
+	if _, ok, err := lookupOwner(name); err != nil || ok {
+		return token, diags
+	}
+	claimed, d := claimOwnership(name, createdAt, false)
+	diags.Append(d...)
+	if diags.HasError() {
+		return token, diags
+	}
+	return types.StringValue(claimed), diags
+}
+
+// checkMachineOwner refuses machines without a marker, machines whose marker
+// belongs to another token, and machines recreated since the marker was written.
+func checkMachineOwner(ctx context.Context, cfg *ClientConfig, name, token string) error {
+	owner, ok, err := lookupOwner(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("machine %q has no ownership marker, so it was not created or adopted by this configuration. Refusing to delete it; set adopt = true and apply to take it over, or remove it from state with terraform state rm", name)
+	}
+	if owner.Token != token {
+		return fmt.Errorf("machine %q is owned by %s. Refusing to delete it; remove it from state with terraform state rm instead", name, owner)
+	}
+	if m, _ := readMachine(ctx, cfg, name); m != nil && owner.CreatedAt != "" && m.CreatedAt.ValueString() != "" && m.CreatedAt.ValueString() != owner.CreatedAt {
+		return fmt.Errorf("machine %q was recreated outside Terraform (created %s, expected %s). Refusing to delete it; remove it from state with terraform state rm instead", name, m.CreatedAt.ValueString(), owner.CreatedAt)
+	}
+	return nil
 }
 
 func (r *MachineResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedPrivateKey, []byte("true"))...)
 }
 
 func readMachine(ctx context.Context, cfg *ClientConfig, name string) (*MachineModel, diag.Diagnostics) {
@@ -482,42 +1594,211 @@ func readMachine(ctx context.Context, cfg *ClientConfig, name string) (*MachineM
 		findLineValue(out, "IP:"),
 		findLineValue(out, "IPv4:"),
 	)
-	ssh := findLineValue(out, "SSH:")
+	arch := firstNonEmpty(
+		findLineValue(out, "Architecture:"),
+		findLineValue(out, "Arch:"),
+	)
+	ipv6 := firstNonEmpty(
+		findLineValue(out, "IPv6:"),
+		findLineValue(out, "IP6:"),
+	)
 	created := firstNonEmpty(
 		findLineValue(out, "Created:"),
 		findLineValue(out, "Creation:"),
 	)
+	distro := findLineValue(out, "Distro:")
+	version := firstNonEmpty(
+		findLineValue(out, "Version:"),
+		findLineValue(out, "Release:"),
+	)
+	// OrbStack serves every machine as name.orb.local
+	fqdn := firstNonEmpty(
+		findLineValue(out, "Domain:"),
+		findLineValue(out, "DNS:"),
+		name+".orb.local",
+	)
 
 	if status != "" {
 		model.Status = types.StringValue(strings.TrimSpace(status))
 	}
 	if ip != "" {
 		model.IPAddress = types.StringValue(strings.TrimSpace(ip))
-		model.SSHHost = model.IPAddress
-		if model.SSHPort.IsNull() || model.SSHPort.IsUnknown() {
-			model.SSHPort = types.Int64Value(22)
-		}
+	}
+	if arch = normalizeArch(arch); arch != "" {
+		model.Emulated = types.BoolValue(arch != hostArch())
+	}
+	if ipv6 != "" {
+		model.IPv6Address = types.StringValue(strings.TrimSpace(ipv6))
 	}
 	if created != "" {
 		model.CreatedAt = types.StringValue(strings.TrimSpace(created))
 	}
-	if ssh != "" {
-		if port := parseSSHPort(ssh); port > 0 {
-			model.SSHPort = types.Int64Value(int64(port))
+	model.FQDN = types.StringValue(strings.TrimSpace(fqdn))
+	if distro = strings.TrimSpace(distro); distro != "" {
+		model.ResolvedImage = types.StringValue(normalizeImage(distro + ":" + strings.TrimSpace(version)))
+	}
+
+	addresses := []attr.Value{}
+	for _, a := range []string{ip, ipv6} {
+		if a = strings.TrimSpace(a); a != "" {
+			addresses = append(addresses, types.StringValue(a))
 		}
 	}
+	model.Addresses = types.ListValueMust(types.StringType, addresses)
+
+	// SSH goes through OrbStack's proxy rather than the machine IP
+	applySSHDetails(model, "")
 
 	return model, diags
 }
 
+// copyMachineInfo copies the attributes read from orb info into dst.
+func copyMachineInfo(dst, src *MachineModel) {
+	dst.IPAddress = src.IPAddress
+	dst.IPv6Address = src.IPv6Address
+	dst.Addresses = src.Addresses
+	dst.FQDN = src.FQDN
+	dst.Status = src.Status
+	dst.CreatedAt = src.CreatedAt
+	// The image never changes, so keep a recorded value when orb info omits it
+	if !src.ResolvedImage.IsNull() || dst.ResolvedImage.IsUnknown() {
+		dst.ResolvedImage = src.ResolvedImage
+	}
+	// Without an architecture in orb info, fall back to the configured arch
+	if !src.Emulated.IsNull() {
+		dst.Emulated = src.Emulated
+	} else if dst.Emulated.IsUnknown() || dst.Emulated.IsNull() {
+		arch := normalizeArch(dst.Arch.ValueString())
+		dst.Emulated = types.BoolValue(arch != "" && arch != hostArch())
+	}
+	applySSHDetails(dst, dst.Username.ValueString())
+}
+
+// machineOSImage reads distro:version from /etc/os-release inside a running machine.
+func machineOSImage(ctx context.Context, cfg *ClientConfig, name string) (string, error) {
+	out, stderr, err := runInMachine(ctx, cfg, name, "", `. /etc/os-release && echo "$ID:${VERSION_CODENAME:-$VERSION_ID}"`)
+	if err != nil {
+		return "", fmt.Errorf("failed to read /etc/os-release: %s", strings.TrimSpace(stderr))
+	}
+	image := strings.TrimSpace(out)
+	if image == "" || strings.HasPrefix(image, ":") {
+		return "", fmt.Errorf("/etc/os-release does not name the distribution")
+	}
+	return normalizeImage(image), nil
+}
+
+// imageDistroNames maps os-release IDs to orb's image names.
+var imageDistroNames = map[string]string{
+	"almalinux":     "alma",
+	"ol":            "oracle",
+	"opensuse-leap": "opensuse",
+}
+
+// imageCodenames maps release numbers to the codenames orb uses as versions.
+var imageCodenames = map[string]map[string]string{
+	"debian": {"10": "buster", "11": "bullseye", "12": "bookworm", "13": "trixie"},
+	"ubuntu": {"20.04": "focal", "22.04": "jammy", "24.04": "noble", "24.10": "oracular", "25.04": "plucky", "25.10": "questing"},
+}
+
+// rollingDistros have no releases; orb names them without a version.
+var rollingDistros = map[string]bool{"arch": true, "gentoo": true, "kali": true, "void": true}
+
+// minorVersionDistros keep major.minor versions in orb's image names.
+var minorVersionDistros = map[string]bool{"alpine": true, "nixos": true, "opensuse": true}
+
+// normalizeImage turns a distro:version from orb info, /etc/os-release or orb
+// images into orb's image naming, so that the sources compare equal: ubuntu
+// and debian use codenames, alpine uses major.minor (3.20.3 is alpine:3.20),
+// other numbered releases use the major version (fedora:40, rocky:9), and
+// rolling distributions have no version.
+func normalizeImage(image string) string {
+	distro, version, _ := strings.Cut(strings.ToLower(strings.TrimSpace(image)), ":")
+	distro = strings.TrimSpace(distro)
+	version = strings.Trim(strings.TrimSpace(version), `"`)
+	if distro == "opensuse-tumbleweed" {
+		return "opensuse:tumbleweed"
+	}
+	if name, ok := imageDistroNames[distro]; ok {
+		distro = name
+	}
+	if version == "" || rollingDistros[distro] || version == "rolling" || strings.HasSuffix(version, "-rolling") {
+		return distro
+	}
+
+	if codenames, ok := imageCodenames[distro]; ok {
+		// 24.04.1 LTS, 24.04 and noble all mean noble
+		num := strings.Fields(version)[0]
+		parts := strings.Split(num, ".")
+		if len(parts) > 2 {
+			num = parts[0] + "." + parts[1]
+		}
+		if codename, ok := codenames[num]; ok {
+			return distro + ":" + codename
+		}
+		if codename, ok := codenames[parts[0]]; ok {
+			return distro + ":" + codename
+		}
+		return distro + ":" + version
+	}
+
+	if version[0] < '0' || version[0] > '9' {
+		return distro + ":" + version
+	}
+	parts := strings.Split(strings.Fields(version)[0], ".")
+	if minorVersionDistros[distro] && len(parts) >= 2 {
+		return distro + ":" + parts[0] + "." + parts[1]
+	}
+	return distro + ":" + parts[0]
+}
+
+// resolveImageAlias returns the distro:version orb currently creates for an
+// image alias without version, based on the default marked in orb images.
+func resolveImageAlias(ctx context.Context, cfg *ClientConfig, alias string) (string, error) {
+	out, _, err := runOrb(ctx, cfg.OrbPath, "images")
+	if err != nil || strings.TrimSpace(out) == "" {
+		out, _, _ = runOrb(ctx, cfg.OrbPath, "image", "list")
+	}
+	alias = strings.ToLower(alias)
+
+	for _, line := range strings.Split(strings.ToLower(out), "\n") {
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		// Lines look like "ubuntu: noble (default), jammy" or list "ubuntu:noble (default)"
+		inline := strings.HasPrefix(fields[0], alias+":") && len(fields[0]) > len(alias)+1
+		if strings.TrimSuffix(fields[0], ":") != alias && !inline {
+			continue
+		}
+		for i, f := range fields {
+			isDefault := strings.HasPrefix(f, "*") ||
+				strings.Contains(f, "(default)") ||
+				(i+1 < len(fields) && strings.Trim(fields[i+1], "()") == "default")
+			if !isDefault {
+				continue
+			}
+			version := strings.Trim(strings.TrimSuffix(strings.TrimPrefix(f, "*"), "(default)"), "():")
+			version = strings.TrimPrefix(version, alias+":")
+			if version != "" && version != alias && version != "default" {
+				return normalizeImage(alias + ":" + version), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("orb images does not mark a default version for %q", alias)
+}
+
+// machineExists reports whether orb knows a machine with the given name.
+func machineExists(ctx context.Context, cfg *ClientConfig, name string) bool {
+	_, _, err := runOrb(ctx, cfg.OrbPath, "info", name)
+	return err == nil
+}
+
 // readUntilReady polls orb info until core fields are populated or timeout elapses.
 func readUntilReady(ctx context.Context, cfg *ClientConfig, name string, timeoutStr string) (*MachineModel, diag.Diagnostics) {
 	var diags diag.Diagnostics
-	timeout, err := time.ParseDuration(timeoutStr)
-	if err != nil || timeout <= 0 {
-		timeout = 30 * time.Second
-	}
-	deadline := time.Now().Add(timeout)
+	deadline := time.Now().Add(parseTimeout(timeoutStr, 30*time.Second))
 	var last *MachineModel
 	for {
 		select {
@@ -540,6 +1821,10 @@ func readUntilReady(ctx context.Context, cfg *ClientConfig, name string, timeout
 	}
 }
 
+func isMachineRunning(m *MachineModel) bool {
+	return strings.EqualFold(strings.TrimSpace(m.Status.ValueString()), "running")
+}
+
 func isMachineReady(m *MachineModel) bool {
 	hasIP := !m.IPAddress.IsNull() && !m.IPAddress.IsUnknown() && strings.TrimSpace(m.IPAddress.ValueString()) != ""
 	hasStatus := !m.Status.IsNull() && !m.Status.IsUnknown() && strings.TrimSpace(m.Status.ValueString()) != ""
@@ -565,15 +1850,82 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
-func parseSSHPort(sshLine string) int {
-	re := regexp.MustCompile(`\-p\s+(\d+)`)
-	m := re.FindStringSubmatch(sshLine)
-	if len(m) == 2 {
-		var p int
-		fmt.Sscanf(m[1], "%d", &p)
-		return p
+// stopMachineGracefully stops a machine and waits until orb reports it stopped.
+func stopMachineGracefully(ctx context.Context, cfg *ClientConfig, name string, timeout time.Duration) error {
+	stopCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, stderr, err := runOrb(stopCtx, cfg.OrbPath, "stop", name); err != nil {
+		if stopCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("orb stop did not finish within delete_timeout (%s)", timeout)
+		}
+		return fmt.Errorf("orb stop failed: %s", strings.TrimSpace(stderr))
+	}
+
+	for {
+		m, _ := readMachine(stopCtx, cfg, name)
+		if m != nil && strings.EqualFold(strings.TrimSpace(m.Status.ValueString()), "stopped") {
+			return nil
+		}
+		select {
+		case <-stopCtx.Done():
+			return fmt.Errorf("machine did not reach stopped within delete_timeout (%s)", timeout)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// restartMachine restarts a machine with orb restart, falling back to a
+// stop/start pair, and waits until it is back.
+func restartMachine(ctx context.Context, cfg *ClientConfig, name string) error {
+	if _, stderr, err := runOrb(ctx, cfg.OrbPath, "restart", name); err != nil {
+		if _, _, stopErr := runOrb(ctx, cfg.OrbPath, "stop", name); stopErr != nil {
+			return fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+		}
+		if _, stderr, err := runOrb(ctx, cfg.OrbPath, "start", name); err != nil {
+			return fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+		}
+	}
+
+	m, diags := readUntilReady(ctx, cfg, name, cfg.CreateTimeout)
+	if diags.HasError() {
+		return fmt.Errorf("failed to read machine after restart")
+	}
+	if m == nil || !isMachineReady(m) {
+		return fmt.Errorf("machine did not come back within create_timeout (%s)", parseTimeout(cfg.CreateTimeout, 30*time.Second))
+	}
+	return nil
+}
+
+// setMachinePassword sets a user's password with chpasswd, passing it on stdin
+// so it never appears in a process list.
+func setMachinePassword(ctx context.Context, cfg *ClientConfig, machine, user, password string) error {
+	if user == "" {
+		return fmt.Errorf("the machine user is unknown")
+	}
+	_, stderr, err := runOrbInput(ctx, cfg.OrbPath, user+":"+password+"\n", "run", "-m", machine, "-u", "root", "chpasswd")
+	if err != nil {
+		return fmt.Errorf("chpasswd failed: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// uniqueMachineName appends a random hex suffix to prefix.
+func uniqueMachineName(prefix string) (string, error) {
+	b := make([]byte, machineNameSuffixLen/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// machineDefaultUser returns the machine's default user as reported inside the machine.
+func machineDefaultUser(ctx context.Context, cfg *ClientConfig, name string) (string, error) {
+	out, stderr, err := runInMachine(ctx, cfg, name, "", "id -un")
+	if err != nil {
+		return "", fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
 	}
-	return 22
+	return strings.TrimSpace(out), nil
 }
 
 // isDefaultMachine checks if the given machine is the current default
@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &MachineExportResource{}
+var _ resource.ResourceWithConfigure = &MachineExportResource{}
+
+func NewMachineExportResource() resource.Resource { return &MachineExportResource{} }
+
+// MachineExportResource exports a machine to an archive on the host via `orb export`.
+type MachineExportResource struct {
+	client *ClientConfig
+}
+
+type MachineExportModel struct {
+	ID            types.String `tfsdk:"id"`
+	Machine       types.String `tfsdk:"machine"`
+	Path          types.String `tfsdk:"path"`
+	Triggers      types.Map    `tfsdk:"triggers"`
+	KeepOnDestroy types.Bool   `tfsdk:"keep_on_destroy"`
+	SizeBytes     types.Int64  `tfsdk:"size_bytes"`
+	SHA256        types.String `tfsdk:"sha256"`
+}
+
+func (r *MachineExportResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_export"
+}
+
+func (r *MachineExportResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Export an OrbStack machine to an archive on the host using `orb export`.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Absolute path of the archive.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the machine to export.",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Required:    true,
+				Description: "Host path of the archive (e.g., ./backups/dev.tar.zst).",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Arbitrary values that re-export the machine when changed.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"keep_on_destroy": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Keep the archive on disk when the resource is destroyed. Default false.",
+			},
+			"size_bytes": schema.Int64Attribute{
+				Computed:    true,
+				Description: "Archive size in bytes.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA-256 checksum of the archive.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *MachineExportResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *MachineExportResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MachineExportModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	dest, err := filepath.Abs(data.Path.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to resolve archive path", err.Error())
+		return
+	}
+
+	if err := exportMachine(ctx, r.client, data.Machine.ValueString(), dest); err != nil {
+		resp.Diagnostics.AddError("Failed to export machine", err.Error())
+		return
+	}
+
+	sum, size, err := fileSHA256(dest)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to checksum archive", err.Error())
+		return
+	}
+
+	data.ID = types.StringValue(dest)
+	data.SizeBytes = types.Int64Value(size)
+	data.SHA256 = types.StringValue(sum)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineExportResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MachineExportModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Checksumming large archives on every refresh is expensive; a missing file
+	// or a size change is treated as the archive being gone.
+	info, err := os.Stat(data.ID.ValueString())
+	if err != nil || info.Size() != data.SizeBytes.ValueInt64() {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineExportResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data MachineExportModel
+
+	// Only keep_on_destroy can change in place
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineExportResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data MachineExportModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.KeepOnDestroy.ValueBool() {
+		return
+	}
+
+	if err := os.Remove(data.ID.ValueString()); err != nil && !os.IsNotExist(err) {
+		resp.Diagnostics.AddError("Failed to remove archive", err.Error())
+	}
+}
+
+// exportMachine writes machine name to dest using orb export, bounded by the
+// create timeout. The archive is written next to dest and renamed into place,
+// so a failed export never replaces or removes an existing archive.
+func exportMachine(ctx context.Context, cfg *ClientConfig, name, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	// A directory of its own keeps the archive's file name for orb export
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dest), ".orbstack-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary archive directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tmp := filepath.Join(tmpDir, filepath.Base(dest))
+
+	timeout := parseTimeout(cfg.CreateTimeout, 5*time.Minute)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, stderr, err := runOrb(runCtx, cfg.OrbPath, "export", name, tmp)
+	if err != nil {
+		if runCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("orb export did not finish within create_timeout (%s)", timeout)
+		}
+		return fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return fmt.Errorf("failed to move archive into place: %w", err)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex SHA-256 checksum and size of a file.
+func fileSHA256(name string) (string, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
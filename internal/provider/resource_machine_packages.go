@@ -0,0 +1,531 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &MachinePackagesResource{}
+var _ resource.ResourceWithConfigure = &MachinePackagesResource{}
+var _ resource.ResourceWithImportState = &MachinePackagesResource{}
+
+func NewMachinePackagesResource() resource.Resource { return &MachinePackagesResource{} }
+
+// MachinePackagesResource installs packages inside a machine with its native package manager.
+type MachinePackagesResource struct {
+	client *ClientConfig
+}
+
+type MachinePackagesModel struct {
+	ID                types.String `tfsdk:"id"`
+	Machine           types.String `tfsdk:"machine"`
+	Packages          types.Set    `tfsdk:"packages"`
+	PackageManager    types.String `tfsdk:"package_manager"`
+	InstalledVersions types.Map    `tfsdk:"installed_versions"`
+}
+
+// installedPrivateKey is the private state key listing the packages this
+// resource installed, as opposed to packages that were present before.
+const installedPrivateKey = "installed_packages"
+
+// packageSpecRegexp matches name or name=version.
+var packageSpecRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._@-]*(=[A-Za-z0-9+.:~_-]+)?$`)
+
+// packageManager describes how to drive one package manager from a shell.
+type packageManager struct {
+	name    string
+	binary  string
+	install func(specs []packageSpec) (string, error)
+	remove  string
+	// query prints the installed version of package $p, or nothing
+	query string
+}
+
+type packageSpec struct {
+	name    string
+	version string
+}
+
+// packageManagers are tried in order when detecting a machine's package manager.
+var packageManagers = []packageManager{
+	{
+		name:   "apt",
+		binary: "apt-get",
+		install: func(specs []packageSpec) (string, error) {
+			return "export DEBIAN_FRONTEND=noninteractive; apt-get update -qq && apt-get install -y -qq --allow-downgrades " + joinPackageSpecs(specs, "=") + " >/dev/null", nil
+		},
+		remove: "DEBIAN_FRONTEND=noninteractive apt-get remove -y -qq",
+		query:  `dpkg-query -W -f='${Status} ${Version}\n' "$p" 2>/dev/null | sed -n 's/^install ok installed //p'`,
+	},
+	{
+		name:   "apk",
+		binary: "apk",
+		install: func(specs []packageSpec) (string, error) {
+			return "apk add -q " + joinPackageSpecs(specs, "="), nil
+		},
+		remove: "apk del -q",
+		query:  `apk list -I "$p" 2>/dev/null | awk -v p="$p" 'index($1, p "-") == 1 { print substr($1, length(p) + 2); exit }'`,
+	},
+	{
+		name:   "dnf",
+		binary: "dnf",
+		install: func(specs []packageSpec) (string, error) {
+			return "dnf install -y -q " + joinPackageSpecs(specs, "-"), nil
+		},
+		remove: "dnf remove -y -q",
+		query:  `rpm -q --qf '%{VERSION}-%{RELEASE}\n' "$p" 2>/dev/null | grep -v 'not installed'`,
+	},
+	{
+		name:   "zypper",
+		binary: "zypper",
+		install: func(specs []packageSpec) (string, error) {
+			return "zypper --non-interactive --quiet install " + joinPackageSpecs(specs, "="), nil
+		},
+		remove: "zypper --non-interactive --quiet remove",
+		query:  `rpm -q --qf '%{VERSION}-%{RELEASE}\n' "$p" 2>/dev/null | grep -v 'not installed'`,
+	},
+	{
+		name:   "pacman",
+		binary: "pacman",
+		install: func(specs []packageSpec) (string, error) {
+			for _, s := range specs {
+				if s.version != "" {
+					return "", fmt.Errorf("pacman cannot install a specific version (%s=%s)", s.name, s.version)
+				}
+			}
+			return "pacman -Sy --noconfirm --needed " + joinPackageSpecs(specs, ""), nil
+		},
+		remove: "pacman -R --noconfirm",
+		query:  `pacman -Q "$p" 2>/dev/null | awk '{ print $2 }'`,
+	},
+}
+
+func (r *MachinePackagesResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_packages"
+}
+
+func (r *MachinePackagesResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Install packages inside an OrbStack machine with its package manager (apt, apk, dnf, zypper or pacman).",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Internal identifier (equals machine).",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the machine.",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"packages": schema.SetAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "Packages to install, as name or name=version with the full version the package manager reports.",
+				Validators: []validator.Set{
+					setvalidator.SizeAtLeast(1),
+					setvalidator.ValueStringsAre(stringvalidator.RegexMatches(packageSpecRegexp, "must be a package name, optionally followed by =version")),
+				},
+			},
+			"package_manager": schema.StringAttribute{
+				Computed:    true,
+				Description: "Detected package manager: apt, apk, dnf, zypper or pacman.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"installed_versions": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Installed version of every package, keyed by package name.",
+			},
+		},
+	}
+}
+
+func (r *MachinePackagesResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *MachinePackagesResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MachinePackagesModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	pm, err := detectPackageManager(ctx, r.client, data.Machine.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to detect package manager", err.Error())
+		return
+	}
+
+	specs := parsePackageSpecs(setToStrings(ctx, data.Packages, &resp.Diagnostics))
+	installed, diags := r.sync(ctx, pm, data.Machine.ValueString(), specs, nil, nil)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(storeInstalledPackages(ctx, resp.Private, installed)...)
+
+	data.ID = data.Machine
+	data.PackageManager = types.StringValue(pm.name)
+	resp.Diagnostics.Append(r.refreshVersions(ctx, pm, &data, specs)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachinePackagesResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MachinePackagesModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	machine := data.Machine.ValueString()
+	if !machineExists(ctx, r.client, machine) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	pm, err := detectPackageManager(ctx, r.client, machine)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to detect package manager", err.Error())
+		return
+	}
+	data.PackageManager = types.StringValue(pm.name)
+
+	specs := parsePackageSpecs(setToStrings(ctx, data.Packages, &resp.Diagnostics))
+	versions, err := queryPackageVersions(ctx, r.client, pm, machine, specs)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to read installed packages", err.Error())
+		return
+	}
+
+	// Packages that were removed or changed version inside the machine drop out
+	// of packages, so the next plan installs them again
+	present := []string{}
+	for _, s := range specs {
+		if v, ok := versions[s.name]; ok && packageVersionMatches(v, s.version) {
+			present = append(present, s.String())
+		}
+	}
+	set, d := types.SetValueFrom(ctx, types.StringType, present)
+	resp.Diagnostics.Append(d...)
+	data.Packages = set
+	m, d := types.MapValueFrom(ctx, types.StringType, versions)
+	resp.Diagnostics.Append(d...)
+	data.InstalledVersions = m
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachinePackagesResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state MachinePackagesModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	pm, err := detectPackageManager(ctx, r.client, plan.Machine.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to detect package manager", err.Error())
+		return
+	}
+
+	want := parsePackageSpecs(setToStrings(ctx, plan.Packages, &resp.Diagnostics))
+	had := parsePackageSpecs(setToStrings(ctx, state.Packages, &resp.Diagnostics))
+	installed, diags := loadInstalledPackages(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	installed, diags = r.sync(ctx, pm, plan.Machine.ValueString(), want, had, installed)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(storeInstalledPackages(ctx, resp.Private, installed)...)
+
+	plan.ID = plan.Machine
+	plan.PackageManager = types.StringValue(pm.name)
+	resp.Diagnostics.Append(r.refreshVersions(ctx, pm, &plan, want)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *MachinePackagesResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data MachinePackagesModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	machine := data.Machine.ValueString()
+	if !machineExists(ctx, r.client, machine) {
+		return
+	}
+
+	pm, err := detectPackageManager(ctx, r.client, machine)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to detect package manager", err.Error())
+		return
+	}
+	// Only packages this resource installed are removed
+	installed, diags := loadInstalledPackages(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || len(installed) == 0 {
+		return
+	}
+	specs := parsePackageSpecs(setToStrings(ctx, data.Packages, &resp.Diagnostics))
+	_, diags = r.sync(ctx, pm, machine, nil, specs, installed)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *MachinePackagesResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("machine"), req, resp)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+}
+
+// sync installs want and removes the packages of had that are no longer wanted
+// and that this resource installed. It returns the packages this resource has
+// installed afterwards: those of installed that are still wanted, plus wanted
+// packages that were not present before.
+func (r *MachinePackagesResource) sync(ctx context.Context, pm packageManager, machine string, want, had []packageSpec, installed []string) ([]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	owned := make(map[string]struct{}, len(installed))
+	for _, name := range installed {
+		owned[name] = struct{}{}
+	}
+	wanted := make(map[string]struct{}, len(want))
+	for _, s := range want {
+		wanted[s.name] = struct{}{}
+	}
+	var remove []string
+	for _, s := range had {
+		_, keep := wanted[s.name]
+		if _, ok := owned[s.name]; ok && !keep {
+			remove = append(remove, shellQuote(s.name))
+		}
+	}
+
+	present, err := queryPackageVersions(ctx, r.client, pm, machine, want)
+	if err != nil {
+		diags.AddError("failed to read installed packages", err.Error())
+		return installed, diags
+	}
+
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	if len(remove) > 0 {
+		fmt.Fprintf(&script, "%s %s\n", pm.remove, strings.Join(remove, " "))
+	}
+	if len(want) > 0 {
+		install, err := pm.install(want)
+		if err != nil {
+			diags.AddAttributeError(path.Root("packages"), "unsupported package version", err.Error())
+			return installed, diags
+		}
+		script.WriteString(install + "\n")
+	}
+
+	if _, stderr, err := runInMachine(ctx, r.client, machine, "root", script.String()); err != nil {
+		diags.AddError(fmt.Sprintf("%s failed", pm.name), fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		return installed, diags
+	}
+
+	result := []string{}
+	for _, s := range want {
+		_, wasOwned := owned[s.name]
+		if _, wasPresent := present[s.name]; wasOwned || !wasPresent {
+			result = append(result, s.name)
+		}
+	}
+	return result, diags
+}
+
+// loadInstalledPackages returns the packages recorded as installed by this
+// resource; imported resources have none.
+func loadInstalledPackages(ctx context.Context, private privateStateGetter) ([]string, diag.Diagnostics) {
+	data, diags := private.GetKey(ctx, installedPrivateKey)
+	if diags.HasError() || data == nil {
+		return nil, diags
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		diags.AddError("failed to read installed packages", err.Error())
+	}
+	return names, diags
+}
+
+// storeInstalledPackages records the packages installed by this resource.
+func storeInstalledPackages(ctx context.Context, private privateStateSetter, names []string) diag.Diagnostics {
+	data, err := json.Marshal(names)
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError("failed to record installed packages", err.Error())
+		return diags
+	}
+	return private.SetKey(ctx, installedPrivateKey, data)
+}
+
+// refreshVersions records the installed versions of specs.
+func (r *MachinePackagesResource) refreshVersions(ctx context.Context, pm packageManager, data *MachinePackagesModel, specs []packageSpec) diag.Diagnostics {
+	var diags diag.Diagnostics
+	versions, err := queryPackageVersions(ctx, r.client, pm, data.Machine.ValueString(), specs)
+	if err != nil {
+		diags.AddError("failed to read installed packages", err.Error())
+		return diags
+	}
+	for _, s := range specs {
+		if _, ok := versions[s.name]; !ok {
+			diags.AddAttributeError(path.Root("packages"), "package not installed", fmt.Sprintf("%s reported success, but %s is not installed.", pm.name, s.name))
+		}
+	}
+	m, d := types.MapValueFrom(ctx, types.StringType, versions)
+	diags.Append(d...)
+	data.InstalledVersions = m
+	return diags
+}
+
+// detectPackageManager returns the first known package manager found in the machine.
+func detectPackageManager(ctx context.Context, cfg *ClientConfig, machine string) (packageManager, error) {
+	var script strings.Builder
+	for _, pm := range packageManagers {
+		fmt.Fprintf(&script, "command -v %s >/dev/null && { echo %s; exit 0; }\n", pm.binary, pm.name)
+	}
+	script.WriteString("exit 0\n")
+
+	out, stderr, err := runInMachine(ctx, cfg, machine, "root", script.String())
+	if err != nil {
+		return packageManager{}, fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+	name := strings.TrimSpace(out)
+	for _, pm := range packageManagers {
+		if pm.name == name {
+			return pm, nil
+		}
+	}
+	return packageManager{}, fmt.Errorf("no supported package manager (apt, apk, dnf, zypper, pacman) found in machine %q", machine)
+}
+
+// queryPackageVersions returns the installed version of each installed package in specs.
+func queryPackageVersions(ctx context.Context, cfg *ClientConfig, pm packageManager, machine string, specs []packageSpec) (map[string]string, error) {
+	versions := make(map[string]string)
+	if len(specs) == 0 {
+		return versions, nil
+	}
+
+	names := make([]string, 0, len(specs))
+	for _, s := range specs {
+		names = append(names, shellQuote(s.name))
+	}
+	script := fmt.Sprintf("for p in %s; do v=$(%s); [ -z \"$v\" ] || echo \"$p=$v\"; done\n", strings.Join(names, " "), pm.query)
+
+	out, stderr, err := runInMachine(ctx, cfg, machine, "root", script)
+	if err != nil {
+		return nil, fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if name, version, ok := strings.Cut(strings.TrimSpace(line), "="); ok && version != "" {
+			versions[name] = strings.TrimSpace(version)
+		}
+	}
+	return versions, nil
+}
+
+func parsePackageSpecs(values []string) []packageSpec {
+	specs := make([]packageSpec, 0, len(values))
+	for _, v := range values {
+		name, version, _ := strings.Cut(v, "=")
+		specs = append(specs, packageSpec{name: name, version: version})
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].name < specs[j].name })
+	return specs
+}
+
+func (s packageSpec) String() string {
+	if s.version == "" {
+		return s.name
+	}
+	return s.name + "=" + s.version
+}
+
+// joinPackageSpecs formats specs for an install command, joining name and
+// version with sep.
+func joinPackageSpecs(specs []packageSpec, sep string) string {
+	args := make([]string, 0, len(specs))
+	for _, s := range specs {
+		arg := s.name
+		if s.version != "" {
+			arg += sep + s.version
+		}
+		args = append(args, shellQuote(arg))
+	}
+	return strings.Join(args, " ")
+}
+
+// packageVersionMatches reports whether an installed version satisfies a
+// pinned version; unpinned packages match any version. Pins are full versions
+// as the package manager reports them, since apt and apk only install exact
+// versions.
+func packageVersionMatches(installed, pinned string) bool {
+	return pinned == "" || installed == pinned
+}
@@ -0,0 +1,517 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &MachinePoolResource{}
+var _ resource.ResourceWithConfigure = &MachinePoolResource{}
+var _ resource.ResourceWithModifyPlan = &MachinePoolResource{}
+
+func NewMachinePoolResource() resource.Resource { return &MachinePoolResource{} }
+
+// MachinePoolResource manages a set of identical machines named from a pattern.
+type MachinePoolResource struct {
+	client *ClientConfig
+}
+
+type MachinePoolModel struct {
+	ID                types.String `tfsdk:"id"`
+	Size              types.Int64  `tfsdk:"size"`
+	NamePattern       types.String `tfsdk:"name_pattern"`
+	Image             types.String `tfsdk:"image"`
+	Arch              types.String `tfsdk:"arch"`
+	Username          types.String `tfsdk:"username"`
+	CloudInit         types.String `tfsdk:"cloud_init"`
+	CreateParallelism types.Int64  `tfsdk:"create_parallelism"`
+	CreateRetries     types.Int64  `tfsdk:"create_retries"`
+	Names             types.List   `tfsdk:"names"`
+	IPAddresses       types.List   `tfsdk:"ip_addresses"`
+	Statuses          types.List   `tfsdk:"statuses"`
+	OwnershipTokens   types.Map    `tfsdk:"ownership_tokens"`
+}
+
+// poolNamePatternRegexp requires a single %d and yields valid machine names.
+var poolNamePatternRegexp = regexp.MustCompile(`^([a-z0-9][a-z0-9-]*)?%d([a-z0-9-]*[a-z0-9])?$`)
+
+const (
+	defaultPoolParallelism = 4
+	defaultPoolRetries     = 1
+)
+
+func (r *MachinePoolResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_pool"
+}
+
+func (r *MachinePoolResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manage a pool of identical OrbStack machines created in parallel.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Internal identifier (equals name_pattern).",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"size": schema.Int64Attribute{
+				Required:    true,
+				Description: "Number of machines in the pool.",
+				Validators:  []validator.Int64{int64validator.AtLeast(1)},
+			},
+			"name_pattern": schema.StringAttribute{
+				Required:    true,
+				Description: "Member name pattern containing %d, replaced by the 1-based member index (e.g., etcd-%d).",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(poolNamePatternRegexp, "must contain a single %d and otherwise only lowercase letters, digits and hyphens"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"image": schema.StringAttribute{
+				Optional:    true,
+				Description: "Base image/distribution for every member. Default ubuntu.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"arch": schema.StringAttribute{
+				Optional:    true,
+				Description: "Architecture passed to orb (-a): amd64 or arm64.",
+				Validators:  []validator.String{stringvalidator.OneOf("amd64", "arm64")},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"username": schema.StringAttribute{
+				Optional:    true,
+				Description: "Username for the default user of every member.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"cloud_init": schema.StringAttribute{
+				Optional:    true,
+				Description: "cloud-init user data passed to every member during creation.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"create_parallelism": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Maximum number of members created at the same time. Default 4.",
+				Validators:  []validator.Int64{int64validator.AtLeast(1)},
+			},
+			"create_retries": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Number of times a failed member is deleted and created again. Default 1.",
+				Validators:  []validator.Int64{int64validator.AtLeast(0)},
+			},
+			"names": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Member names, ordered by index.",
+			},
+			"ip_addresses": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Member IP addresses, ordered by index.",
+			},
+			"statuses": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Member statuses, ordered by index.",
+			},
+			"ownership_tokens": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Ownership token of every member, keyed by member name. Only these members are ever deleted.",
+			},
+		},
+	}
+}
+
+func (r *MachinePoolResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *MachinePoolResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to plan on create or destroy
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var state MachinePoolModel
+	var size types.Int64
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("size"), &size)...)
+	if resp.Diagnostics.HasError() || size.IsUnknown() {
+		return
+	}
+
+	// Members that failed to create or were removed outside Terraform are
+	// missing from state; plan an update that creates them
+	if int64(len(state.Names.Elements())) != size.ValueInt64() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("names"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ip_addresses"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("statuses"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ownership_tokens"), types.MapUnknown(types.StringType))...)
+	}
+}
+
+func (r *MachinePoolResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MachinePoolModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	data.ID = data.NamePattern
+
+	// Members that were created are kept in state
+	members, diags := r.createMembers(ctx, data, poolIndexes(1, int(data.Size.ValueInt64())))
+	appendMemberDiags(&resp.Diagnostics, diags, len(members))
+	resp.Diagnostics.Append(r.refreshMembers(ctx, &data, members)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachinePoolResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MachinePoolModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	// Members removed outside Terraform drop out of names; size stays as
+	// configured, so the next plan recreates them
+	members := poolMembers(ctx, data, &resp.Diagnostics)
+	resp.Diagnostics.Append(r.refreshMembers(ctx, &data, members)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachinePoolResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state MachinePoolModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	size := int(data.Size.ValueInt64())
+	pattern := data.NamePattern.ValueString()
+	members := poolMembers(ctx, state, &resp.Diagnostics)
+
+	// Scale down from the highest index first, touching recorded members only
+	for _, name := range sortedPoolMembers(pattern, members, true) {
+		if i, _ := poolMemberIndex(pattern, name); i <= size {
+			continue
+		}
+		if err := deletePoolMember(ctx, r.client, name, members[name]); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("failed to delete pool member %s", name), err.Error())
+			break
+		}
+		delete(members, name)
+	}
+
+	// Recreate missing members and scale up
+	if !resp.Diagnostics.HasError() {
+		var missing []int
+		for _, i := range poolIndexes(1, size) {
+			if _, ok := members[fmt.Sprintf(pattern, i)]; !ok {
+				missing = append(missing, i)
+			}
+		}
+		created, diags := r.createMembers(ctx, data, missing)
+		for name, token := range created {
+			members[name] = token
+		}
+		appendMemberDiags(&resp.Diagnostics, diags, len(members))
+	}
+
+	// Record what exists even on failure, so no member is forgotten
+	resp.Diagnostics.Append(r.refreshMembers(ctx, &data, members)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachinePoolResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data MachinePoolModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	members := poolMembers(ctx, data, &resp.Diagnostics)
+	for _, name := range sortedPoolMembers(data.NamePattern.ValueString(), members, true) {
+		if err := deletePoolMember(ctx, r.client, name, members[name]); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("failed to delete pool member %s", name), err.Error())
+		}
+	}
+}
+
+// createMembers creates the given member indexes with bounded parallelism,
+// retrying each failed member and reporting failures per member. It returns the
+// ownership token of every member that was created.
+func (r *MachinePoolResource) createMembers(ctx context.Context, data MachinePoolModel, indexes []int) (map[string]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	created := make(map[string]string)
+	if len(indexes) == 0 {
+		return created, diags
+	}
+
+	parallelism := defaultPoolParallelism
+	if !data.CreateParallelism.IsNull() && !data.CreateParallelism.IsUnknown() {
+		parallelism = int(data.CreateParallelism.ValueInt64())
+	}
+	retries := defaultPoolRetries
+	if !data.CreateRetries.IsNull() && !data.CreateRetries.IsUnknown() {
+		retries = int(data.CreateRetries.ValueInt64())
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	for _, i := range indexes {
+		name := fmt.Sprintf(data.NamePattern.ValueString(), i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			token, d := r.createMember(ctx, data, name, retries)
+			mu.Lock()
+			diags.Append(d...)
+			if token != "" {
+				created[name] = token
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return created, diags
+}
+
+// appendMemberDiags reports member creation failures. While the pool has
+// members they are warnings, so a partly created pool is not tainted and the
+// next plan creates the missing members; a pool without members fails.
+func appendMemberDiags(dst *diag.Diagnostics, diags diag.Diagnostics, members int) {
+	for _, d := range diags {
+		if d.Severity() == diag.SeverityError && members > 0 {
+			dst.AddWarning(d.Summary(), d.Detail()+"\nThe next apply creates the missing member.")
+			continue
+		}
+		dst.Append(d)
+	}
+}
+
+// createMember creates one member and records this configuration as its owner.
+// A machine that already exists under the member name is never taken over.
+func (r *MachinePoolResource) createMember(ctx context.Context, data MachinePoolModel, name string, retries int) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if machineExists(ctx, r.client, name) {
+		diags.AddError(fmt.Sprintf("failed to create pool member %s", name), fmt.Sprintf("A machine named %q already exists and was not created by this pool.", name))
+		return "", diags
+	}
+
+	member := &MachineModel{
+		Name:      types.StringValue(name),
+		Image:     data.Image,
+		Arch:      data.Arch,
+		Username:  data.Username,
+		CloudInit: data.CloudInit,
+	}
+
+	var lastErr string
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			// Remove whatever a failed attempt left behind
+			_, _, _ = runOrb(ctx, r.client.OrbPath, "delete", name)
+		}
+
+		args, cleanup, d := machineCreateArgs(ctx, r.client, member, name)
+		if d.HasError() {
+			cleanup()
+			return "", d
+		}
+		_, stderr, err := runOrb(ctx, r.client.OrbPath, args...)
+		cleanup()
+		if err != nil {
+			lastErr = fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr))
+			continue
+		}
+
+		model, _ := readUntilReady(ctx, r.client, name, r.client.CreateTimeout)
+		if model == nil || !isMachineReady(model) {
+			lastErr = "machine did not become ready within create_timeout"
+			continue
+		}
+
+		owner, err := currentOwner(model.CreatedAt.ValueString())
+		if err == nil {
+			err = claimMachine(name, owner)
+		}
+		if err != nil {
+			// Without a marker the member could never be deleted again
+			_, _, _ = runOrb(ctx, r.client.OrbPath, "delete", name)
+			diags.AddError(fmt.Sprintf("failed to record ownership of pool member %s", name), err.Error())
+			return "", diags
+		}
+		return owner.Token, diags
+	}
+
+	diags.AddError(fmt.Sprintf("failed to create pool member %s", name), fmt.Sprintf("%s (after %d attempts)", lastErr, retries+1))
+	return "", diags
+}
+
+// deletePoolMember deletes a recorded member after checking that this pool
+// owns it. A member that is already gone is not an error.
+func deletePoolMember(ctx context.Context, cfg *ClientConfig, name, token string) error {
+	if !machineExists(ctx, cfg, name) {
+		return releaseMachine(name, token)
+	}
+	if err := checkMachineOwner(ctx, cfg, name, token); err != nil {
+		return err
+	}
+	if _, stderr, err := runOrb(ctx, cfg.OrbPath, "delete", name); err != nil {
+		return fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+	return releaseMachine(name, token)
+}
+
+// poolMembers returns the recorded members of a pool, mapping each member name
+// to its ownership token.
+func poolMembers(ctx context.Context, data MachinePoolModel, diags *diag.Diagnostics) map[string]string {
+	members := make(map[string]string)
+	if data.OwnershipTokens.IsNull() || data.OwnershipTokens.IsUnknown() {
+		return members
+	}
+	diags.Append(data.OwnershipTokens.ElementsAs(ctx, &members, false)...)
+	return members
+}
+
+// refreshMembers reads the recorded members and fills the computed attributes.
+// Members that no longer exist are dropped silently, so the next plan can
+// recreate them.
+func (r *MachinePoolResource) refreshMembers(ctx context.Context, data *MachinePoolModel, members map[string]string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	names := []attr.Value{}
+	ips := []attr.Value{}
+	statuses := []attr.Value{}
+	tokens := map[string]attr.Value{}
+	for _, name := range sortedPoolMembers(data.NamePattern.ValueString(), members, false) {
+		if !machineExists(ctx, r.client, name) {
+			continue
+		}
+		m, d := readMachine(ctx, r.client, name)
+		diags.Append(d...)
+		if m == nil {
+			continue
+		}
+		names = append(names, m.Name)
+		ips = append(ips, types.StringValue(m.IPAddress.ValueString()))
+		statuses = append(statuses, types.StringValue(m.Status.ValueString()))
+		tokens[name] = types.StringValue(members[name])
+	}
+
+	data.Names = types.ListValueMust(types.StringType, names)
+	data.IPAddresses = types.ListValueMust(types.StringType, ips)
+	data.Statuses = types.ListValueMust(types.StringType, statuses)
+	data.OwnershipTokens = types.MapValueMust(types.StringType, tokens)
+	return diags
+}
+
+// sortedPoolMembers returns the member names ordered by index.
+func sortedPoolMembers(pattern string, members map[string]string, descending bool) []string {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		i, _ := poolMemberIndex(pattern, names[a])
+		j, _ := poolMemberIndex(pattern, names[b])
+		if descending {
+			return i > j
+		}
+		return i < j
+	})
+	return names
+}
+
+// poolMemberIndex extracts the member index from a name built from pattern.
+func poolMemberIndex(pattern, name string) (int, bool) {
+	prefix, suffix, _ := strings.Cut(pattern, "%d")
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) || len(name) <= len(prefix)+len(suffix) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(name[len(prefix) : len(name)-len(suffix)])
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+// poolIndexes returns the member indexes from..to inclusive.
+func poolIndexes(from, to int) []int {
+	var out []int
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
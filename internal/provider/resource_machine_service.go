@@ -0,0 +1,394 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &MachineServiceResource{}
+var _ resource.ResourceWithConfigure = &MachineServiceResource{}
+var _ resource.ResourceWithImportState = &MachineServiceResource{}
+
+func NewMachineServiceResource() resource.Resource { return &MachineServiceResource{} }
+
+// MachineServiceResource manages a systemd or OpenRC service inside a machine.
+type MachineServiceResource struct {
+	client *ClientConfig
+}
+
+type MachineServiceModel struct {
+	ID              types.String `tfsdk:"id"`
+	Machine         types.String `tfsdk:"machine"`
+	Name            types.String `tfsdk:"name"`
+	Enabled         types.Bool   `tfsdk:"enabled"`
+	State           types.String `tfsdk:"state"`
+	RestartTriggers types.Map    `tfsdk:"restart_triggers"`
+	InitSystem      types.String `tfsdk:"init_system"`
+	ActiveState     types.String `tfsdk:"active_state"`
+}
+
+var serviceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9@._:-]+$`)
+
+// initSystem describes how to drive one init system from a shell. Commands use
+// the service name in $s.
+type initSystem struct {
+	name string
+	// reload makes the init system pick up changed unit files
+	reload  string
+	enable  string
+	disable string
+	start   string
+	stop    string
+	restart string
+	// read prints exists=, enabled= and active= lines for $s
+	read string
+}
+
+var initSystems = map[string]initSystem{
+	"systemd": {
+		name:    "systemd",
+		reload:  `systemctl daemon-reload`,
+		enable:  `systemctl enable -q "$s"`,
+		disable: `systemctl disable -q "$s"`,
+		start:   `systemctl start "$s"`,
+		stop:    `systemctl stop "$s"`,
+		restart: `systemctl restart "$s"`,
+		read: `[ "$(systemctl show -p LoadState --value "$s")" = not-found ] && echo exists=false || echo exists=true
+echo "enabled=$(systemctl is-enabled "$s" 2>/dev/null)"
+echo "active=$(systemctl is-active "$s" 2>/dev/null)"`,
+	},
+	"openrc": {
+		name:    "openrc",
+		enable:  `rc-update -q add "$s" default`,
+		disable: `rc-update -q del "$s" default || true`,
+		start:   `rc-service -q "$s" start`,
+		stop:    `rc-service -q "$s" stop`,
+		restart: `rc-service -q "$s" restart`,
+		read: `rc-service -e "$s" && echo exists=true || echo exists=false
+rc-update show default 2>/dev/null | awk '{ print $1 }' | grep -qx "$s" && echo enabled=enabled || echo enabled=disabled
+rc-service "$s" status 2>/dev/null | sed -n 's/.*status: *//p' | sed 's/^/active=/'`,
+	},
+}
+
+func (r *MachineServiceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_service"
+}
+
+func (r *MachineServiceResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manage a systemd or OpenRC service inside an OrbStack machine.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Identifier in the form machine/name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the machine.",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Service name (e.g., docker, postgresql, sshd).",
+				Validators:  []validator.String{stringvalidator.RegexMatches(serviceNameRegexp, "must be a valid service name")},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"enabled": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Start the service at boot. Unmanaged when unset.",
+			},
+			"state": schema.StringAttribute{
+				Optional:    true,
+				Description: "Desired state: running or stopped. Unmanaged when unset.",
+				Validators:  []validator.String{stringvalidator.OneOf("running", "stopped")},
+			},
+			"restart_triggers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Arbitrary values that restart the service when changed.",
+			},
+			"init_system": schema.StringAttribute{
+				Computed:    true,
+				Description: "Detected init system: systemd or openrc.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"active_state": schema.StringAttribute{
+				Computed:    true,
+				Description: "State reported by the init system (e.g., active, inactive, failed, started, stopped).",
+			},
+		},
+	}
+}
+
+func (r *MachineServiceResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *MachineServiceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MachineServiceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	data.ID = types.StringValue(data.Machine.ValueString() + "/" + data.Name.ValueString())
+	resp.Diagnostics.Append(r.apply(ctx, &data, MachineServiceModel{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineServiceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MachineServiceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	machine := data.Machine.ValueString()
+	if !machineExists(ctx, r.client, machine) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	sys, err := detectInitSystem(ctx, r.client, machine)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to detect init system", err.Error())
+		return
+	}
+	status, err := readServiceStatus(ctx, r.client, sys, machine, data.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to read service", err.Error())
+		return
+	}
+	if !status.exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	data.InitSystem = types.StringValue(sys.name)
+	applyServiceStatus(&data, status)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineServiceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state MachineServiceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, &plan, state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *MachineServiceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// The service is left as it is; only Terraform stops managing it
+}
+
+func (r *MachineServiceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	machine, name, ok := strings.Cut(req.ID, "/")
+	if !ok || machine == "" || name == "" {
+		resp.Diagnostics.AddError("invalid import ID", fmt.Sprintf("Expected machine/service, got %q.", req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("machine"), machine)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+}
+
+// apply brings the service from prior to plan and records the resulting status.
+// A restart_triggers change restarts a running service; it is skipped when the
+// service is stopped in the same apply.
+func (r *MachineServiceResource) apply(ctx context.Context, plan *MachineServiceModel, prior MachineServiceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	machine, name := plan.Machine.ValueString(), plan.Name.ValueString()
+
+	sys, err := detectInitSystem(ctx, r.client, machine)
+	if err != nil {
+		diags.AddError("failed to detect init system", err.Error())
+		return diags
+	}
+	status, err := readServiceStatus(ctx, r.client, sys, machine, name)
+	if err != nil {
+		diags.AddError("failed to read service", err.Error())
+		return diags
+	}
+	if !status.exists {
+		diags.AddAttributeError(path.Root("name"), "service not found", fmt.Sprintf("%s has no service named %q in machine %q.", sys.name, name, machine))
+		return diags
+	}
+
+	var actions []string
+	reload := false
+	if !plan.Enabled.IsNull() && plan.Enabled.ValueBool() != status.enabled {
+		if plan.Enabled.ValueBool() {
+			actions = append(actions, sys.enable)
+			reload = true
+		} else {
+			actions = append(actions, sys.disable)
+		}
+	}
+	switch want := plan.State.ValueString(); {
+	case want == "running" && !status.running:
+		actions = append(actions, sys.start)
+		reload = true
+	case want == "stopped" && status.running:
+		actions = append(actions, sys.stop)
+	case want != "stopped" && status.running && !prior.ID.IsNull() && !plan.RestartTriggers.Equal(prior.RestartTriggers):
+		actions = append(actions, sys.restart)
+		reload = true
+	}
+	// Unit files pushed or changed since the last reload must not start or
+	// restart with their old definition
+	if reload && sys.reload != "" {
+		actions = append([]string{sys.reload}, actions...)
+	}
+
+	var script strings.Builder
+	fmt.Fprintf(&script, "set -e\ns=%s\n", shellQuote(name))
+	for _, action := range actions {
+		script.WriteString(action + "\n")
+	}
+
+	if _, stderr, err := runInMachine(ctx, r.client, machine, "root", script.String()); err != nil {
+		diags.AddError("failed to manage service", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		return diags
+	}
+
+	status, err = readServiceStatus(ctx, r.client, sys, machine, name)
+	if err != nil {
+		diags.AddError("failed to read service", err.Error())
+		return diags
+	}
+	plan.InitSystem = types.StringValue(sys.name)
+	applyServiceStatus(plan, status)
+	return diags
+}
+
+// serviceStatus is a service as reported by the init system.
+type serviceStatus struct {
+	exists  bool
+	enabled bool
+	running bool
+	active  string
+}
+
+// applyServiceStatus copies status into data; enabled and state are only
+// reconciled when managed.
+func applyServiceStatus(data *MachineServiceModel, status serviceStatus) {
+	data.ActiveState = types.StringValue(status.active)
+	if !data.Enabled.IsNull() {
+		data.Enabled = types.BoolValue(status.enabled)
+	}
+	if !data.State.IsNull() {
+		data.State = types.StringValue("stopped")
+		if status.running {
+			data.State = types.StringValue("running")
+		}
+	}
+}
+
+// detectInitSystem returns systemd when it runs as PID 1, otherwise OpenRC.
+func detectInitSystem(ctx context.Context, cfg *ClientConfig, machine string) (initSystem, error) {
+	script := "if [ -d /run/systemd/system ]; then echo systemd; elif command -v rc-service >/dev/null; then echo openrc; fi"
+	out, stderr, err := runInMachine(ctx, cfg, machine, "root", script)
+	if err != nil {
+		return initSystem{}, fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+	sys, ok := initSystems[strings.TrimSpace(out)]
+	if !ok {
+		return initSystem{}, fmt.Errorf("no supported init system (systemd, openrc) found in machine %q", machine)
+	}
+	return sys, nil
+}
+
+func readServiceStatus(ctx context.Context, cfg *ClientConfig, sys initSystem, machine, name string) (serviceStatus, error) {
+	script := fmt.Sprintf("s=%s\n%s\ntrue\n", shellQuote(name), sys.read)
+	out, stderr, err := runInMachine(ctx, cfg, machine, "root", script)
+	if err != nil {
+		return serviceStatus{}, fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+
+	var status serviceStatus
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "exists":
+			status.exists = value == "true"
+		case "enabled":
+			// systemd reports enabled, enabled-runtime, static, alias, ...
+			status.enabled = strings.HasPrefix(value, "enabled")
+		case "active":
+			status.active = value
+		}
+	}
+	status.running = status.active == "active" || status.active == "activating" || status.active == "reloading" || status.active == "started"
+	if status.active == "" {
+		status.active = "unknown"
+	}
+	return status, nil
+}
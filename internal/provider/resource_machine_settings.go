@@ -0,0 +1,378 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &MachineSettingsResource{}
+var _ resource.ResourceWithConfigure = &MachineSettingsResource{}
+var _ resource.ResourceWithImportState = &MachineSettingsResource{}
+
+func NewMachineSettingsResource() resource.Resource { return &MachineSettingsResource{} }
+
+// MachineSettingsResource manages system settings inside a machine via `orb run`.
+type MachineSettingsResource struct {
+	client *ClientConfig
+}
+
+type MachineSettingsModel struct {
+	ID              types.String `tfsdk:"id"`
+	Machine         types.String `tfsdk:"machine"`
+	Hostname        types.String `tfsdk:"hostname"`
+	HostnameAliases types.List   `tfsdk:"hostname_aliases"`
+	Timezone        types.String `tfsdk:"timezone"`
+	Locale          types.String `tfsdk:"locale"`
+	Environment     types.Map    `tfsdk:"environment"`
+}
+
+// managedBlockMarker delimits the lines this resource owns in /etc/hosts and /etc/environment.
+const managedBlockMarker = "terraform-provider-orbstack"
+
+var (
+	hostnameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+	timezoneRegexp = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+	localeRegexp   = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+	envKeyRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	envValueRegexp = regexp.MustCompile(`^[^"\r\n]*$`)
+)
+
+func (r *MachineSettingsResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_settings"
+}
+
+func (r *MachineSettingsResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	hostnameValidator := stringvalidator.RegexMatches(hostnameRegexp, "must be a valid hostname")
+	resp.Schema = schema.Schema{
+		Description: "Manage hostname, timezone, locale and environment inside an OrbStack machine. Unset arguments are left unmanaged.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Internal identifier (equals machine).",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the machine.",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"hostname": schema.StringAttribute{
+				Optional:    true,
+				Description: "Hostname inside the machine (defaults to the machine name in OrbStack).",
+				Validators:  []validator.String{hostnameValidator},
+			},
+			"hostname_aliases": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Extra names resolving to 127.0.0.1 inside the machine, managed in /etc/hosts.",
+				Validators:  []validator.List{listvalidator.ValueStringsAre(hostnameValidator)},
+			},
+			"timezone": schema.StringAttribute{
+				Optional:    true,
+				Description: "Timezone (e.g., Europe/Amsterdam).",
+				Validators:  []validator.String{stringvalidator.RegexMatches(timezoneRegexp, "must be a timezone name such as Europe/Amsterdam")},
+			},
+			"locale": schema.StringAttribute{
+				Optional:    true,
+				Description: "System locale, written as LANG (e.g., en_US.UTF-8).",
+				Validators:  []validator.String{stringvalidator.RegexMatches(localeRegexp, "must be a locale name such as en_US.UTF-8")},
+			},
+			"environment": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Variables managed in /etc/environment.",
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(stringvalidator.RegexMatches(envKeyRegexp, "must be a valid environment variable name")),
+					mapvalidator.ValueStringsAre(stringvalidator.RegexMatches(envValueRegexp, "must not contain double quotes or line breaks")),
+				},
+			},
+		},
+	}
+}
+
+func (r *MachineSettingsResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *MachineSettingsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MachineSettingsModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, data, MachineSettingsModel{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.ID = data.Machine
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineSettingsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MachineSettingsModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	machine := data.Machine.ValueString()
+	if !machineExists(ctx, r.client, machine) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	current, err := readMachineSettings(ctx, r.client, machine)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to read machine settings", err.Error())
+		return
+	}
+
+	// Only managed (non-null) settings are reconciled
+	if !data.Hostname.IsNull() {
+		data.Hostname = types.StringValue(current.hostname)
+	}
+	if !data.Timezone.IsNull() {
+		data.Timezone = types.StringValue(current.timezone)
+	}
+	if !data.Locale.IsNull() {
+		data.Locale = types.StringValue(current.locale)
+	}
+	if !data.HostnameAliases.IsNull() {
+		list, d := types.ListValueFrom(ctx, types.StringType, current.aliases)
+		resp.Diagnostics.Append(d...)
+		data.HostnameAliases = list
+	}
+	if !data.Environment.IsNull() {
+		m, d := types.MapValueFrom(ctx, types.StringType, current.environment)
+		resp.Diagnostics.Append(d...)
+		data.Environment = m
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineSettingsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state MachineSettingsModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, plan, state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = plan.Machine
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *MachineSettingsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data MachineSettingsModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	// Hostname, timezone and locale have no known original value and are kept;
+	// the managed blocks in /etc/hosts and /etc/environment are removed.
+	machine := data.Machine.ValueString()
+	if !machineExists(ctx, r.client, machine) {
+		return
+	}
+	script := replaceManagedBlock("/etc/hosts", nil) + replaceManagedBlock("/etc/environment", nil)
+	if _, stderr, err := runInMachine(ctx, r.client, machine, "root", script); err != nil {
+		resp.Diagnostics.AddError("failed to remove machine settings", fmt.Sprintf("orb error: %s", stderr))
+	}
+}
+
+func (r *MachineSettingsResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("machine"), req, resp)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+}
+
+// apply writes the settings of plan that differ from prior in a single orb run.
+// Settings that become unset are released: their managed blocks are removed,
+// other values are left as they are.
+func (r *MachineSettingsResource) apply(ctx context.Context, plan, prior MachineSettingsModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var script strings.Builder
+	script.WriteString("set -e\n")
+
+	if v := plan.Hostname.ValueString(); !plan.Hostname.IsNull() && !plan.Hostname.Equal(prior.Hostname) {
+		fmt.Fprintf(&script, "hostname %[1]s; echo %[1]s > /etc/hostname\n", shellQuote(v))
+	}
+	if v := plan.Timezone.ValueString(); !plan.Timezone.IsNull() && !plan.Timezone.Equal(prior.Timezone) {
+		fmt.Fprintf(&script, `tz=%s
+[ -f "/usr/share/zoneinfo/$tz" ] || { echo "unknown timezone $tz (is tzdata installed?)" >&2; exit 1; }
+ln -sf "/usr/share/zoneinfo/$tz" /etc/localtime
+echo "$tz" > /etc/timezone
+`, shellQuote(v))
+	}
+	if v := plan.Locale.ValueString(); !plan.Locale.IsNull() && !plan.Locale.Equal(prior.Locale) {
+		fmt.Fprintf(&script, `loc=%s
+if [ -f /etc/locale.gen ] && command -v locale-gen >/dev/null; then
+  sed -i "s/^# *\($loc[ .]\)/\1/" /etc/locale.gen
+  grep -q "^$loc[ .]" /etc/locale.gen || echo "$loc ${loc#*.}" >> /etc/locale.gen
+  locale-gen >/dev/null
+fi
+echo "LANG=$loc" > /etc/locale.conf
+[ ! -d /etc/default ] || echo "LANG=$loc" > /etc/default/locale
+`, shellQuote(v))
+	}
+	if !plan.HostnameAliases.Equal(prior.HostnameAliases) && !(plan.HostnameAliases.IsNull() && prior.HostnameAliases.IsNull()) {
+		var lines []string
+		if aliases := listToStrings(ctx, plan.HostnameAliases, &diags); len(aliases) > 0 {
+			lines = []string{"127.0.0.1 " + strings.Join(aliases, " ")}
+		}
+		script.WriteString(replaceManagedBlock("/etc/hosts", lines))
+	}
+	if !plan.Environment.Equal(prior.Environment) && !(plan.Environment.IsNull() && prior.Environment.IsNull()) {
+		env := make(map[string]string)
+		diags.Append(plan.Environment.ElementsAs(ctx, &env, false)...)
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var lines []string
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf(`%s="%s"`, k, env[k]))
+		}
+		script.WriteString(replaceManagedBlock("/etc/environment", lines))
+	}
+	if diags.HasError() {
+		return diags
+	}
+
+	if _, stderr, err := runInMachine(ctx, r.client, plan.Machine.ValueString(), "root", script.String()); err != nil {
+		diags.AddError("failed to apply machine settings", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+	}
+	return diags
+}
+
+// replaceManagedBlock returns a script replacing this provider's block in file
+// with lines. The file is rewritten in place to keep its inode and mode.
+func replaceManagedBlock(file string, lines []string) string {
+	var block strings.Builder
+	if len(lines) > 0 {
+		fmt.Fprintf(&block, "# BEGIN %s\n%s\n# END %s\n", managedBlockMarker, strings.Join(lines, "\n"), managedBlockMarker)
+	}
+	return fmt.Sprintf(`f=%[1]s; touch "$f"; tmp=$(mktemp)
+sed '/^# BEGIN %[2]s$/,/^# END %[2]s$/d' "$f" > "$tmp"
+printf '%%s' %[3]s >> "$tmp"
+cat "$tmp" > "$f"; rm -f "$tmp"
+`, shellQuote(file), managedBlockMarker, shellQuote(block.String()))
+}
+
+// machineSettings holds settings read from inside a machine.
+type machineSettings struct {
+	hostname    string
+	timezone    string
+	locale      string
+	aliases     []string
+	environment map[string]string
+}
+
+// readMachineSettings reads all settings in one orb run.
+func readMachineSettings(ctx context.Context, cfg *ClientConfig, machine string) (machineSettings, error) {
+	script := fmt.Sprintf(`echo "hostname=$(hostname)"
+tz=$(readlink /etc/localtime 2>/dev/null | sed 's|.*/zoneinfo/||')
+[ -n "$tz" ] || tz=$(cat /etc/timezone 2>/dev/null)
+echo "timezone=$tz"
+echo "locale=$( . /etc/default/locale 2>/dev/null; . /etc/locale.conf 2>/dev/null; echo "$LANG")"
+sed -n '/^# BEGIN %[1]s$/,/^# END %[1]s$/p' /etc/hosts 2>/dev/null | grep -v '^#' | sed 's/^/hosts=/'
+sed -n '/^# BEGIN %[1]s$/,/^# END %[1]s$/p' /etc/environment 2>/dev/null | grep -v '^#' | sed 's/^/env=/'
+true
+`, managedBlockMarker)
+
+	out, stderr, err := runInMachine(ctx, cfg, machine, "root", script)
+	if err != nil {
+		return machineSettings{}, fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+
+	s := machineSettings{aliases: []string{}, environment: map[string]string{}}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "hostname":
+			s.hostname = value
+		case "timezone":
+			s.timezone = value
+		case "locale":
+			s.locale = value
+		case "hosts":
+			if fields := strings.Fields(value); len(fields) > 1 {
+				s.aliases = append(s.aliases, fields[1:]...)
+			}
+		case "env":
+			if k, v, ok := strings.Cut(value, "="); ok {
+				s.environment[k] = strings.Trim(v, `"`)
+			}
+		}
+	}
+	return s, nil
+}
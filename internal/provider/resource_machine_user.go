@@ -0,0 +1,448 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = &MachineUserResource{}
+var _ resource.ResourceWithConfigure = &MachineUserResource{}
+var _ resource.ResourceWithImportState = &MachineUserResource{}
+
+func NewMachineUserResource() resource.Resource { return &MachineUserResource{} }
+
+// MachineUserResource manages an additional Linux user inside a machine.
+type MachineUserResource struct {
+	client *ClientConfig
+}
+
+type MachineUserModel struct {
+	ID                types.String `tfsdk:"id"`
+	Machine           types.String `tfsdk:"machine"`
+	Name              types.String `tfsdk:"name"`
+	Groups            types.Set    `tfsdk:"groups"`
+	Shell             types.String `tfsdk:"shell"`
+	Sudo              types.Bool   `tfsdk:"sudo"`
+	SSHAuthorizedKeys types.List   `tfsdk:"ssh_authorized_keys"`
+	UID               types.Int64  `tfsdk:"uid"`
+	Home              types.String `tfsdk:"home"`
+}
+
+// linuxNameRegexp matches portable user and group names.
+var linuxNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+func (r *MachineUserResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_user"
+}
+
+func (r *MachineUserResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	nameValidator := stringvalidator.RegexMatches(linuxNameRegexp, "must start with a lowercase letter or underscore and contain only lowercase letters, digits, underscores and hyphens")
+	resp.Schema = schema.Schema{
+		Description: "Manage a Linux user inside an OrbStack machine.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Identifier in the form machine/name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"machine": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the machine.",
+				Validators:  []validator.String{stringvalidator.LengthAtLeast(1)},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "User name.",
+				Validators:  []validator.String{stringvalidator.LengthBetween(1, 32), nameValidator},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"groups": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Supplementary groups. Missing groups are created.",
+				Validators:  []validator.Set{setvalidator.ValueStringsAre(nameValidator)},
+			},
+			"shell": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Login shell (e.g., /bin/bash). Defaults to the machine's useradd default.",
+				Validators:  []validator.String{stringvalidator.RegexMatches(regexp.MustCompile(`^/`), "must be an absolute path")},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"sudo": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Allow passwordless sudo for all commands. Default false.",
+			},
+			"ssh_authorized_keys": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Public keys written to the user's ~/.ssh/authorized_keys, replacing its content.",
+			},
+			"uid": schema.Int64Attribute{
+				Computed:    true,
+				Description: "Numeric user ID.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"home": schema.StringAttribute{
+				Computed:    true,
+				Description: "Home directory.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *MachineUserResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*ClientConfig)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClientConfig, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *MachineUserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MachineUserModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	name := data.Name.ValueString()
+	groups := setToStrings(ctx, data.Groups, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var script strings.Builder
+	script.WriteString("set -e\ncommand -v useradd >/dev/null || { echo 'useradd not found (on Alpine, install the shadow package)' >&2; exit 1; }\n")
+	script.WriteString(ensureGroupsScript(groups))
+	args := []string{"useradd", "-m"}
+	if v := data.Shell.ValueString(); !data.Shell.IsUnknown() && v != "" {
+		args = append(args, "-s", shellQuote(v))
+	}
+	if len(groups) > 0 {
+		args = append(args, "-G", shellQuote(strings.Join(groups, ",")))
+	}
+	args = append(args, shellQuote(name))
+	script.WriteString(strings.Join(args, " ") + "\n")
+	script.WriteString(sudoersScript(name, data.Sudo.ValueBool()))
+	if !data.SSHAuthorizedKeys.IsNull() {
+		script.WriteString(userAuthorizedKeysScript(name, listToStrings(ctx, data.SSHAuthorizedKeys, &resp.Diagnostics)))
+	}
+
+	machine := data.Machine.ValueString()
+	if _, stderr, err := runInMachine(ctx, r.client, machine, "root", script.String()); err != nil {
+		resp.Diagnostics.AddError("failed to create user", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		return
+	}
+
+	resp.Diagnostics.Append(r.refresh(ctx, &data)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineUserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MachineUserModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	if !machineExists(ctx, r.client, data.Machine.ValueString()) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	info, err := readMachineUser(ctx, r.client, data.Machine.ValueString(), data.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to read user", err.Error())
+		return
+	}
+	if info == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(applyMachineUserInfo(ctx, &data, info)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MachineUserResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state MachineUserModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	name := plan.Name.ValueString()
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	if v := plan.Shell.ValueString(); !plan.Shell.IsUnknown() && v != "" && !plan.Shell.Equal(state.Shell) {
+		fmt.Fprintf(&script, "usermod -s %s %s\n", shellQuote(v), shellQuote(name))
+	}
+	if !plan.Groups.Equal(state.Groups) && !plan.Groups.IsNull() {
+		groups := setToStrings(ctx, plan.Groups, &resp.Diagnostics)
+		script.WriteString(ensureGroupsScript(groups))
+		fmt.Fprintf(&script, "usermod -G %s %s\n", shellQuote(strings.Join(groups, ",")), shellQuote(name))
+	}
+	if !plan.Sudo.Equal(state.Sudo) {
+		script.WriteString(sudoersScript(name, plan.Sudo.ValueBool()))
+	}
+	if !plan.SSHAuthorizedKeys.Equal(state.SSHAuthorizedKeys) && !plan.SSHAuthorizedKeys.IsNull() {
+		script.WriteString(userAuthorizedKeysScript(name, listToStrings(ctx, plan.SSHAuthorizedKeys, &resp.Diagnostics)))
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if _, stderr, err := runInMachine(ctx, r.client, plan.Machine.ValueString(), "root", script.String()); err != nil {
+		resp.Diagnostics.AddError("failed to update user", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+		return
+	}
+
+	resp.Diagnostics.Append(r.refresh(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *MachineUserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data MachineUserModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("provider not configured", "missing client configuration")
+		return
+	}
+
+	machine := data.Machine.ValueString()
+	if !machineExists(ctx, r.client, machine) {
+		return
+	}
+
+	name := shellQuote(data.Name.ValueString())
+	script := fmt.Sprintf("set -e\n%sif getent passwd %[2]s >/dev/null; then userdel -r %[2]s 2>/dev/null || userdel %[2]s; fi\n", sudoersScript(data.Name.ValueString(), false), name)
+	if _, stderr, err := runInMachine(ctx, r.client, machine, "root", script); err != nil {
+		resp.Diagnostics.AddError("failed to delete user", fmt.Sprintf("orb error: %s", strings.TrimSpace(stderr)))
+	}
+}
+
+func (r *MachineUserResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	machine, name, ok := strings.Cut(req.ID, "/")
+	if !ok || machine == "" || name == "" {
+		resp.Diagnostics.AddError("invalid import ID", fmt.Sprintf("Expected machine/username, got %q.", req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("machine"), machine)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+}
+
+// refresh fills the computed attributes after a change.
+func (r *MachineUserResource) refresh(ctx context.Context, data *MachineUserModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	data.ID = types.StringValue(data.Machine.ValueString() + "/" + data.Name.ValueString())
+
+	info, err := readMachineUser(ctx, r.client, data.Machine.ValueString(), data.Name.ValueString())
+	if err != nil {
+		diags.AddError("failed to read user", err.Error())
+		return diags
+	}
+	if info == nil {
+		diags.AddError("user not found after apply", data.ID.ValueString())
+		return diags
+	}
+	data.UID = types.Int64Value(info.uid)
+	data.Home = types.StringValue(info.home)
+	data.Shell = types.StringValue(info.shell)
+	return diags
+}
+
+// machineUserInfo holds a user as read from inside a machine.
+type machineUserInfo struct {
+	uid    int64
+	home   string
+	shell  string
+	groups []string
+	sudo   bool
+	keys   []string
+}
+
+// readMachineUser returns nil when the user does not exist.
+func readMachineUser(ctx context.Context, cfg *ClientConfig, machine, name string) (*machineUserInfo, error) {
+	script := fmt.Sprintf(`u=%s
+p=$(getent passwd "$u") || { echo missing; exit 0; }
+echo "passwd=$p"
+primary=$(id -gn "$u")
+for g in $(id -Gn "$u"); do [ "$g" = "$primary" ] || echo "group=$g"; done
+[ ! -f %s ] || echo "sudo=true"
+home=$(echo "$p" | cut -d: -f6)
+[ ! -f "$home/.ssh/authorized_keys" ] || sed 's/^/key=/' "$home/.ssh/authorized_keys"
+`, shellQuote(name), shellQuote(sudoersFile(name)))
+
+	out, stderr, err := runInMachine(ctx, cfg, machine, "root", script)
+	if err != nil {
+		return nil, fmt.Errorf("orb error: %s", strings.TrimSpace(stderr))
+	}
+
+	info := &machineUserInfo{groups: []string{}, keys: []string{}}
+	found := false
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "passwd":
+			fields := strings.Split(value, ":")
+			if len(fields) < 7 {
+				return nil, fmt.Errorf("unexpected passwd entry %q", value)
+			}
+			found = true
+			info.uid, _ = strconv.ParseInt(fields[2], 10, 64)
+			info.home = fields[5]
+			info.shell = fields[6]
+		case "group":
+			info.groups = append(info.groups, value)
+		case "sudo":
+			info.sudo = true
+		case "key":
+			if k := strings.TrimSpace(value); k != "" && !strings.HasPrefix(k, "#") {
+				info.keys = append(info.keys, k)
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	sort.Strings(info.groups)
+	return info, nil
+}
+
+// applyMachineUserInfo copies a read user into data. Groups and keys are only
+// reconciled when managed.
+func applyMachineUserInfo(ctx context.Context, data *MachineUserModel, info *machineUserInfo) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var d diag.Diagnostics
+
+	data.UID = types.Int64Value(info.uid)
+	data.Home = types.StringValue(info.home)
+	data.Shell = types.StringValue(info.shell)
+	if !data.Sudo.IsNull() || info.sudo {
+		data.Sudo = types.BoolValue(info.sudo)
+	}
+	if !data.Groups.IsNull() {
+		data.Groups, d = types.SetValueFrom(ctx, types.StringType, info.groups)
+		diags.Append(d...)
+	}
+	if !data.SSHAuthorizedKeys.IsNull() {
+		data.SSHAuthorizedKeys, d = types.ListValueFrom(ctx, types.StringType, info.keys)
+		diags.Append(d...)
+	}
+	return diags
+}
+
+// ensureGroupsScript creates groups that do not exist yet.
+func ensureGroupsScript(groups []string) string {
+	var b strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&b, "getent group %[1]s >/dev/null || groupadd %[1]s\n", shellQuote(g))
+	}
+	return b.String()
+}
+
+// sudoersFile is the drop-in granting sudo to a managed user.
+func sudoersFile(name string) string {
+	return "/etc/sudoers.d/terraform-" + name
+}
+
+// sudoersScript grants or revokes passwordless sudo.
+func sudoersScript(name string, enabled bool) string {
+	file := shellQuote(sudoersFile(name))
+	if !enabled {
+		return fmt.Sprintf("rm -f %s\n", file)
+	}
+	return fmt.Sprintf("mkdir -p /etc/sudoers.d; printf '%%s\\n' %s > %s; chmod 0440 %s\n", shellQuote(name+" ALL=(ALL) NOPASSWD:ALL"), file, file)
+}
+
+// userAuthorizedKeysScript replaces a user's authorized_keys with keys.
+func userAuthorizedKeysScript(name string, keys []string) string {
+	content := ""
+	if len(keys) > 0 {
+		content = strings.Join(keys, "\n") + "\n"
+	}
+	return fmt.Sprintf(`home=$(getent passwd %[1]s | cut -d: -f6)
+mkdir -p "$home/.ssh"; printf '%%s' %[2]s > "$home/.ssh/authorized_keys"
+chmod 700 "$home/.ssh"; chmod 600 "$home/.ssh/authorized_keys"
+chown -R %[1]s: "$home/.ssh"
+`, shellQuote(name), shellQuote(content))
+}
+
+// setToStrings converts a set of strings, returning nil for null or unknown sets.
+func setToStrings(ctx context.Context, s types.Set, diags *diag.Diagnostics) []string {
+	if s.IsNull() || s.IsUnknown() {
+		return nil
+	}
+	var out []string
+	diags.Append(s.ElementsAs(ctx, &out, false)...)
+	sort.Strings(out)
+	return out
+}
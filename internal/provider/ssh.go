@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Defaults for OrbStack's SSH proxy, used when ~/.orbstack/ssh/config is missing.
+const (
+	orbSSHDefaultHost = "localhost"
+	orbSSHDefaultPort = 32222
+	orbSSHDefaultKey  = "~/.orbstack/ssh/id_ed25519"
+)
+
+// orbSSHEndpoint describes how to reach OrbStack's SSH proxy from the host.
+type orbSSHEndpoint struct {
+	Host         string
+	Port         int64
+	IdentityFile string
+}
+
+// loadOrbSSHEndpoint reads the "Host orb" entry of ~/.orbstack/ssh/config and
+// falls back to OrbStack's documented defaults for anything it cannot find.
+func loadOrbSSHEndpoint() orbSSHEndpoint {
+	ep := orbSSHEndpoint{
+		Host:         orbSSHDefaultHost,
+		Port:         orbSSHDefaultPort,
+		IdentityFile: expandHome(orbSSHDefaultKey),
+	}
+
+	f, err := os.Open(expandHome("~/.orbstack/ssh/config"))
+	if err != nil {
+		return ep
+	}
+	defer f.Close()
+
+	inOrb := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		key, value := strings.ToLower(fields[0]), strings.Join(fields[1:], " ")
+		if key == "host" {
+			inOrb = false
+			for _, h := range fields[1:] {
+				if h == "orb" {
+					inOrb = true
+				}
+			}
+			continue
+		}
+		if !inOrb {
+			continue
+		}
+		switch key {
+		case "hostname":
+			ep.Host = value
+		case "port":
+			if p, err := strconv.ParseInt(value, 10, 64); err == nil && p > 0 {
+				ep.Port = p
+			}
+		case "identityfile":
+			ep.IdentityFile = expandHome(strings.Trim(value, `"`))
+		}
+	}
+	return ep
+}
+
+// machineSSHUser returns the proxy login for a machine: machine, or user@machine
+// when a specific user is requested.
+func machineSSHUser(machine, user string) string {
+	if user = strings.TrimSpace(user); user != "" {
+		return user + "@" + machine
+	}
+	return machine
+}
+
+// applySSHDetails fills the SSH connection attributes of m for the given user.
+func applySSHDetails(m *MachineModel, user string) {
+	ep := loadOrbSSHEndpoint()
+	login := machineSSHUser(m.Name.ValueString(), user)
+
+	m.SSHUser = types.StringValue(login)
+	m.SSHHost = types.StringValue(ep.Host)
+	m.SSHPort = types.Int64Value(ep.Port)
+	m.SSHPrivateKeyPath = types.StringValue(ep.IdentityFile)
+	m.SSHCommand = types.StringValue(fmt.Sprintf("ssh -i %s -p %d %s@%s", shellQuote(ep.IdentityFile), ep.Port, login, ep.Host))
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
+
+// readPublicKey reads an OpenSSH public key file, accepting the private key path
+// when the matching .pub file exists next to it.
+func readPublicKey(p string) (string, error) {
+	p = expandHome(p)
+	if !strings.HasSuffix(p, ".pub") {
+		if _, err := os.Stat(p + ".pub"); err == nil {
+			p += ".pub"
+		}
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(b))
+	if strings.Contains(key, "PRIVATE KEY") || len(strings.Fields(key)) < 2 {
+		return "", fmt.Errorf("%s does not contain an OpenSSH public key", p)
+	}
+	return key, nil
+}
+
+// desiredAuthorizedKeys combines the provider's default_ssh_key_path with the
+// machine's ssh_authorized_keys, without duplicates.
+func desiredAuthorizedKeys(cfg *ClientConfig, keys []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]struct{})
+	add := func(k string) {
+		k = strings.TrimSpace(k)
+		if _, ok := seen[k]; ok || k == "" {
+			return
+		}
+		seen[k] = struct{}{}
+		out = append(out, k)
+	}
+	if cfg != nil && cfg.DefaultSSHKeyPath != "" {
+		k, err := readPublicKey(cfg.DefaultSSHKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("default_ssh_key_path: %w", err)
+		}
+		add(k)
+	}
+	for _, k := range keys {
+		add(k)
+	}
+	return out, nil
+}
+
+// syncAuthorizedKeys adds and removes keys in the machine user's ~/.ssh/authorized_keys.
+func syncAuthorizedKeys(ctx context.Context, cfg *ClientConfig, machine, user string, add, remove []string) error {
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+	var script strings.Builder
+	script.WriteString("set -e; umask 077; mkdir -p ~/.ssh; touch ~/.ssh/authorized_keys\n")
+	for _, k := range remove {
+		fmt.Fprintf(&script, "grep -vxF %s ~/.ssh/authorized_keys > ~/.ssh/authorized_keys.tmp || true; mv ~/.ssh/authorized_keys.tmp ~/.ssh/authorized_keys\n", shellQuote(k))
+	}
+	for _, k := range add {
+		fmt.Fprintf(&script, "grep -qxF %[1]s ~/.ssh/authorized_keys || echo %[1]s >> ~/.ssh/authorized_keys\n", shellQuote(k))
+	}
+	_, stderr, err := runInMachine(ctx, cfg, machine, user, script.String())
+	if err != nil {
+		return fmt.Errorf("failed to update authorized_keys: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// presentAuthorizedKeys returns the subset of keys found in the machine user's authorized_keys.
+func presentAuthorizedKeys(ctx context.Context, cfg *ClientConfig, machine, user string, keys []string) ([]string, error) {
+	out, stderr, err := runInMachine(ctx, cfg, machine, user, "cat ~/.ssh/authorized_keys 2>/dev/null || true")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorized_keys: %s", strings.TrimSpace(stderr))
+	}
+	lines := make(map[string]struct{})
+	for _, line := range strings.Split(out, "\n") {
+		lines[strings.TrimSpace(line)] = struct{}{}
+	}
+	present := []string{}
+	for _, k := range keys {
+		if _, ok := lines[k]; ok {
+			present = append(present, k)
+		}
+	}
+	return present, nil
+}
+
+// stringsDiff returns the elements of a that are not in b.
+func stringsDiff(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, v := range b {
+		in[v] = struct{}{}
+	}
+	var out []string
+	for _, v := range a {
+		if _, ok := in[v]; !ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}